@@ -36,6 +36,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&PXBucketClaim{},
 		&PXBucketAccess{},
 		&PXBucketClass{},
+		&PXBucket{},
+		&PXBucketAccessClass{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -3,6 +3,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // +genclient
@@ -11,8 +12,10 @@ import (
 // PXBucketClaim is a user's request for a bucket
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Namespaced,shortName=pbc
+// +kubebuilder:subresource:status
 // +groupName=object.portworx.io
 // +kubebuilder:printcolumn:name="Provisioned",type=string,JSONPath=`.status.provisioned`,description="Indicates whether the bucket has been provisioned for this claim"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="The current lifecycle phase of this bucketclaim"
 // +kubebuilder:printcolumn:name="BucketID",type=string,JSONPath=`.status.bucketId`,description="Indicates the bucket ID for this provisioned bucketclaim"
 // +kubebuilder:printcolumn:name="BackendType",type=string,JSONPath=`.status.backendType`,description="Indicates the backend type for this provisioned bucketclaim"
 type PXBucketClaim struct {
@@ -48,8 +51,56 @@ type BucketClaimSpec struct {
 	// requested by the PXBucketClaim.
 	// Required.
 	BucketClassName string `json:"bucketClassName,omitempty" protobuf:"bytes,1,opt,name=bucketClassName"`
+
+	// BucketName is the name of an existing PXBucket to bind to.
+	// If unset, a new bucket is provisioned.
+	// +optional
+	BucketName string `json:"bucketName,omitempty" protobuf:"bytes,2,opt,name=bucketName"`
+
+	// ExistingBucketName is the name of a bucket that already exists on the backend.
+	// The bucket is adopted by the claim instead of creating a new one.
+	// +optional
+	ExistingBucketName string `json:"existingBucketName,omitempty" protobuf:"bytes,3,opt,name=existingBucketName"`
+
+	// Region is the region of the existing bucket. Defaults to the region of the PXBucketClass.
+	// Only valid with existingBucketName.
+	// +optional
+	Region string `json:"region,omitempty" protobuf:"bytes,4,opt,name=region"`
+
+	// Endpoint is the endpoint of the existing bucket. Defaults to the endpoint of the PXBucketClass.
+	// Only valid with existingBucketName.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty" protobuf:"bytes,5,opt,name=endpoint"`
+
+	// DeletionPolicy is the deletion policy of the existing bucket. Defaults to Retain.
+	// Only valid with existingBucketName.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty" protobuf:"bytes,6,opt,name=deletionPolicy"`
+
+	// AnonymousAccessMode overrides the anonymous access mode of the PXBucketClass.
+	// Only allowed if the PXBucketClass permits overrides.
+	// +optional
+	AnonymousAccessMode AnonymousAccessMode `json:"anonymousAccessMode,omitempty" protobuf:"bytes,7,opt,name=anonymousAccessMode"`
 }
 
+// AnonymousAccessMode describes the access to a bucket without credentials
+// +kubebuilder:validation:Enum=Private;ReadOnly;WriteOnly;ReadWrite
+type AnonymousAccessMode string
+
+const (
+	// AnonymousAccessPrivate allows no anonymous access
+	AnonymousAccessPrivate AnonymousAccessMode = "Private"
+
+	// AnonymousAccessReadOnly allows anonymous reads of objects
+	AnonymousAccessReadOnly AnonymousAccessMode = "ReadOnly"
+
+	// AnonymousAccessWriteOnly allows anonymous writes of objects
+	AnonymousAccessWriteOnly AnonymousAccessMode = "WriteOnly"
+
+	// AnonymousAccessReadWrite allows anonymous reads and writes of objects
+	AnonymousAccessReadWrite AnonymousAccessMode = "ReadWrite"
+)
+
 // BucketStatus is the status of the PXBucketClaim
 type BucketClaimStatus struct {
 	// provisioned indicates if the bucket is created.
@@ -75,8 +126,86 @@ type BucketClaimStatus struct {
 	// Endpoint is the endpoint that this bucket was provisioned with
 	// +optional
 	Endpoint string `json:"endpoint" protobuf:"varint,6,opt,name=endpoint"`
+
+	// phase is the current lifecycle phase of the PXBucketClaim
+	// +optional
+	Phase BucketPhase `json:"phase,omitempty" protobuf:"bytes,7,opt,name=phase"`
+
+	// lastTransitionTime is the last time the phase changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,8,opt,name=lastTransitionTime"`
+
+	// observedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,9,opt,name=observedGeneration"`
+
+	// lastError is the message of the last error encountered while reconciling
+	// +optional
+	LastError string `json:"lastError,omitempty" protobuf:"bytes,10,opt,name=lastError"`
+
+	// conditions are the latest available observations of the PXBucketClaim state
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" protobuf:"bytes,12,rep,name=conditions"`
+
+	// clearBucket indicates whether the bucket contents are removed before the bucket is deleted
+	// +optional
+	ClearBucket bool `json:"clearBucket,omitempty" protobuf:"varint,13,opt,name=clearBucket"`
+
+	// bucketName is the name of the PXBucket bound to this claim
+	// +optional
+	BucketName string `json:"bucketName,omitempty" protobuf:"bytes,14,opt,name=bucketName"`
+
+	// anonymousAccessMode is the anonymous access mode applied to the bucket
+	// +optional
+	AnonymousAccessMode AnonymousAccessMode `json:"anonymousAccessMode,omitempty" protobuf:"bytes,15,opt,name=anonymousAccessMode"`
 }
 
+// BucketPhase describes the lifecycle phase of a PXBucketClaim or PXBucketAccess
+type BucketPhase string
+
+const (
+	// BucketPhasePending means the object has not been processed yet
+	BucketPhasePending BucketPhase = "Pending"
+
+	// BucketPhaseProvisioning means the bucket or access is being created on the backend
+	BucketPhaseProvisioning BucketPhase = "Provisioning"
+
+	// BucketPhaseReady means the bucket or access is available for use
+	BucketPhaseReady BucketPhase = "Ready"
+
+	// BucketPhaseFailed means the last reconcile attempt failed and will be retried
+	BucketPhaseFailed BucketPhase = "Failed"
+
+	// BucketPhaseDeleting means the bucket or access is being removed from the backend
+	BucketPhaseDeleting BucketPhase = "Deleting"
+)
+
+// Condition types set on PXBucketClaims and PXBucketAccesses
+const (
+	// ConditionReady indicates the bucket or access is available for use
+	ConditionReady = "Ready"
+
+	// ConditionProvisioning indicates the bucket or access is being created on the backend
+	ConditionProvisioning = "Provisioning"
+
+	// ConditionFailed indicates the last reconcile attempt failed
+	ConditionFailed = "Failed"
+
+	// ConditionDeleting indicates the bucket or access is being removed from the backend
+	ConditionDeleting = "Deleting"
+
+	// ConditionWaitingForBucket indicates the PXBucketAccess waits for its PXBucketClaim to be provisioned
+	ConditionWaitingForBucket = "WaitingForBucket"
+
+	// ConditionLost indicates the bucket of a provisioned PXBucketClaim no longer exists on the backend
+	ConditionLost = "Lost"
+
+	// ConditionDegraded indicates the bucket or the access key of a granted PXBucketAccess no longer exists on the backend
+	ConditionDegraded = "Degraded"
+)
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -122,6 +251,48 @@ const (
 	PXBucketClaimRetain DeletionPolicy = "Retain"
 )
 
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PXBucketAccessClass is an admin's template for the access policy of a bucket access
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=pbaclass
+// +groupName=object.portworx.io
+type PXBucketAccessClass struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// policyTemplate is a Go template rendering the IAM policy document
+	// granted to a PXBucketAccess. If unset, the policy of the access mode is used.
+	// +optional
+	PolicyTemplate string `json:"policyTemplate,omitempty" protobuf:"bytes,2,opt,name=policyTemplate"`
+
+	// credentialsFormat is the default layout of the credentials secrets of
+	// PXBucketAccesses referencing this class.
+	// +optional
+	CredentialsFormat CredentialsFormat `json:"credentialsFormat,omitempty" protobuf:"bytes,3,opt,name=credentialsFormat"`
+
+	// credentialsTemplate maps credentials secret keys to Go templates
+	// rendering their values. Used by the Template credentials format.
+	// +optional
+	CredentialsTemplate map[string]string `json:"credentialsTemplate,omitempty" protobuf:"bytes,4,rep,name=credentialsTemplate"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// PXBucketAccessClassList is a list of PXBucketAccessClass objects
+type PXBucketAccessClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// List of PXBucketAccessClasses
+	Items []PXBucketAccessClass `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // PXBucketClassList is a list of PXBucketClass objects
 type PXBucketClassList struct {
@@ -139,11 +310,14 @@ type PXBucketClassList struct {
 // PXBucketAccess is a user's request to access a bucket
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Namespaced,shortName=pba
+// +kubebuilder:subresource:status
 // +groupName=object.portworx.io
 // +kubebuilder:printcolumn:name="AccessGranted",type=boolean,JSONPath=`.status.accessGranted`,description="Indicates if access has been granted for a given bucket"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="The current lifecycle phase of this access object"
 // +kubebuilder:printcolumn:name="CredentialsSecretName",type=string,JSONPath=`.status.credentialsSecretName`,description="The secret with connection info for the bucket"
 // +kubebuilder:printcolumn:name="BucketID",type=string,JSONPath=`.status.bucketId`,description="The bucket ID for this access object"
 // +kubebuilder:printcolumn:name="BackendType",type=string,JSONPath=`.status.backendType`,description="The backend type for this access object"
+// +kubebuilder:printcolumn:name="NextRotation",type=date,JSONPath=`.status.nextRotationTime`,description="The time the access key is due for rotation",priority=1
 type PXBucketAccess struct {
 	metav1.TypeMeta `json:",inline"`
 	// Standard object's metadata.
@@ -174,8 +348,76 @@ type BucketAccessSpec struct {
 	// ExistingBucketId is the bucket ID to provide access to.
 	// +optional
 	ExistingBucketId string `json:"existingBucketId,omitempty" protobuf:"bytes,3,opt,name=existingBucketId"`
+
+	// AccessMode is the access granted to the bucket. Defaults to ReadWrite.
+	// +optional
+	AccessMode BucketAccessMode `json:"accessMode,omitempty" protobuf:"bytes,4,opt,name=accessMode"`
+
+	// BucketAccessClassName is the name of the PXBucketAccessClass
+	// providing the access policy template.
+	// +optional
+	BucketAccessClassName string `json:"bucketAccessClassName,omitempty" protobuf:"bytes,5,opt,name=bucketAccessClassName"`
+
+	// RotationPeriod is the maximum age of the access key before the
+	// controller rotates it. Overrides the default of the PXBucketClass.
+	// +optional
+	RotationPeriod *metav1.Duration `json:"rotationPeriod,omitempty" protobuf:"bytes,6,opt,name=rotationPeriod"`
+
+	// CredentialsFormat is the layout of the credentials secret. Overrides
+	// the format of the PXBucketAccessClass. Defaults to Default.
+	// +optional
+	CredentialsFormat CredentialsFormat `json:"credentialsFormat,omitempty" protobuf:"bytes,7,opt,name=credentialsFormat"`
+
+	// CredentialsSecretName is the name of the secret the credentials are
+	// written to. Defaults to px-os-credentials-<name>. An existing secret
+	// that is not owned by this PXBucketAccess is never overwritten.
+	// +optional
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty" protobuf:"bytes,8,opt,name=credentialsSecretName"`
 }
 
+// BucketAccessMode describes the access granted to a bucket
+// +kubebuilder:validation:Enum=ReadOnly;ReadWrite;WriteOnly
+type BucketAccessMode string
+
+const (
+	// BucketAccessReadOnly allows reading and listing objects
+	BucketAccessReadOnly BucketAccessMode = "ReadOnly"
+
+	// BucketAccessReadWrite allows all operations on the bucket
+	BucketAccessReadWrite BucketAccessMode = "ReadWrite"
+
+	// BucketAccessWriteOnly allows writing objects
+	BucketAccessWriteOnly BucketAccessMode = "WriteOnly"
+)
+
+// CredentialsFormat describes the layout of the credentials secret of a PXBucketAccess
+// +kubebuilder:validation:Enum=Default;AWSEnv;AWSConfig;JSON;Rclone;S3cmd;Template
+type CredentialsFormat string
+
+const (
+	// CredentialsFormatDefault stores access-key-id, secret-access-key,
+	// endpoint, region and bucket-id keys
+	CredentialsFormatDefault CredentialsFormat = "Default"
+
+	// CredentialsFormatAWSEnv stores the environment variables of the AWS SDKs
+	CredentialsFormatAWSEnv CredentialsFormat = "AWSEnv"
+
+	// CredentialsFormatAWSConfig stores AWS shared credentials and config files
+	CredentialsFormatAWSConfig CredentialsFormat = "AWSConfig"
+
+	// CredentialsFormatJSON stores a credentials.json document
+	CredentialsFormatJSON CredentialsFormat = "JSON"
+
+	// CredentialsFormatRclone stores an rclone.conf remote
+	CredentialsFormatRclone CredentialsFormat = "Rclone"
+
+	// CredentialsFormatS3cmd stores an s3cmd .s3cfg file
+	CredentialsFormatS3cmd CredentialsFormat = "S3cmd"
+
+	// CredentialsFormatTemplate renders the credentialsTemplate of the PXBucketAccessClass
+	CredentialsFormatTemplate CredentialsFormat = "Template"
+)
+
 // BucketStatus is the status of the PXBucketClaim
 type BucketAccessStatus struct {
 	// accessGranted indicates if the bucket access is created.
@@ -197,6 +439,57 @@ type BucketAccessStatus struct {
 	// backendType is the backend type that this PXBucketClaim was created with
 	// +optional
 	BackendType string `json:"backendType" protobuf:"bytes,5,opt,name=backendType"`
+
+	// phase is the current lifecycle phase of the PXBucketAccess
+	// +optional
+	Phase BucketPhase `json:"phase,omitempty" protobuf:"bytes,6,opt,name=phase"`
+
+	// lastTransitionTime is the last time the phase changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,7,opt,name=lastTransitionTime"`
+
+	// observedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" protobuf:"varint,8,opt,name=observedGeneration"`
+
+	// lastError is the message of the last error encountered while reconciling
+	// +optional
+	LastError string `json:"lastError,omitempty" protobuf:"bytes,9,opt,name=lastError"`
+
+	// conditions are the latest available observations of the PXBucketAccess state
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" protobuf:"bytes,11,rep,name=conditions"`
+
+	// accessMode is the access mode that was granted
+	// +optional
+	AccessMode BucketAccessMode `json:"accessMode,omitempty" protobuf:"bytes,12,opt,name=accessMode"`
+
+	// accessKeyId is the ID of the access key in the credentials secret
+	// +optional
+	AccessKeyId string `json:"accessKeyId,omitempty" protobuf:"bytes,13,opt,name=accessKeyId"`
+
+	// keyCreationTime is the time the current access key was created
+	// +optional
+	KeyCreationTime *metav1.Time `json:"keyCreationTime,omitempty" protobuf:"bytes,14,opt,name=keyCreationTime"`
+
+	// nextRotationTime is the time the access key is due for rotation
+	// +optional
+	NextRotationTime *metav1.Time `json:"nextRotationTime,omitempty" protobuf:"bytes,15,opt,name=nextRotationTime"`
+
+	// previousAccessKeyId is the ID of the replaced access key that stays
+	// valid until previousKeyRevocationTime
+	// +optional
+	PreviousAccessKeyId string `json:"previousAccessKeyId,omitempty" protobuf:"bytes,16,opt,name=previousAccessKeyId"`
+
+	// previousKeyRevocationTime is the time the previous access key is revoked
+	// +optional
+	PreviousKeyRevocationTime *metav1.Time `json:"previousKeyRevocationTime,omitempty" protobuf:"bytes,17,opt,name=previousKeyRevocationTime"`
+
+	// credentialsFormat is the layout the credentials secret was rendered with
+	// +optional
+	CredentialsFormat CredentialsFormat `json:"credentialsFormat,omitempty" protobuf:"bytes,18,opt,name=credentialsFormat"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -209,3 +502,120 @@ type PXBucketAccessList struct {
 	// List of PXBucketAccess
 	Items []PXBucketAccess `json:"items" protobuf:"bytes,2,rep,name=items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PXBucket represents a bucket on the backend. It is bound to at most one PXBucketClaim.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=pb
+// +kubebuilder:subresource:status
+// +groupName=object.portworx.io
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="The binding phase of this bucket"
+// +kubebuilder:printcolumn:name="BucketID",type=string,JSONPath=`.spec.bucketId`,description="The bucket ID on the backend"
+// +kubebuilder:printcolumn:name="Claim",type=string,JSONPath=`.spec.claimRef.name`,description="The PXBucketClaim bound to this bucket"
+// +kubebuilder:printcolumn:name="DeletionPolicy",type=string,JSONPath=`.spec.deletionPolicy`,description="The deletion policy for this bucket"
+type PXBucket struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// spec describes the backend bucket.
+	// Required.
+	Spec BucketSpec `json:"spec" protobuf:"bytes,2,opt,name=spec"`
+
+	// status represents the binding state of the bucket.
+	// +optional
+	Status *BucketStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+// BucketSpec describes a bucket on the backend
+type BucketSpec struct {
+	// bucketId is the bucket ID on the backend
+	// Required.
+	BucketID string `json:"bucketId" protobuf:"bytes,1,opt,name=bucketId"`
+
+	// region is the region of the bucket
+	// +optional
+	Region string `json:"region,omitempty" protobuf:"bytes,2,opt,name=region"`
+
+	// endpoint is the endpoint of the bucket
+	// +optional
+	Endpoint string `json:"endpoint,omitempty" protobuf:"bytes,3,opt,name=endpoint"`
+
+	// backendType is the backend type of the bucket
+	// Required.
+	BackendType string `json:"backendType" protobuf:"bytes,4,opt,name=backendType"`
+
+	// deletionPolicy determines whether the backend bucket is deleted
+	// when the bound PXBucketClaim is deleted.
+	// Required.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy" protobuf:"bytes,5,opt,name=deletionPolicy"`
+
+	// clearBucket indicates whether the bucket contents are removed before the bucket is deleted
+	// +optional
+	ClearBucket bool `json:"clearBucket,omitempty" protobuf:"varint,6,opt,name=clearBucket"`
+
+	// bucketClassName is the name of the PXBucketClass the bucket was provisioned with
+	// +optional
+	BucketClassName string `json:"bucketClassName,omitempty" protobuf:"bytes,7,opt,name=bucketClassName"`
+
+	// claimRef is the PXBucketClaim bound to this bucket. A released bucket
+	// keeps the reference to its last claim. Clearing it makes the bucket
+	// available to claims in any namespace.
+	// +optional
+	ClaimRef *BucketClaimReference `json:"claimRef,omitempty" protobuf:"bytes,8,opt,name=claimRef"`
+}
+
+// BucketClaimReference references a PXBucketClaim
+type BucketClaimReference struct {
+	// namespace of the PXBucketClaim
+	Namespace string `json:"namespace" protobuf:"bytes,1,opt,name=namespace"`
+
+	// name of the PXBucketClaim
+	Name string `json:"name" protobuf:"bytes,2,opt,name=name"`
+
+	// uid of the PXBucketClaim
+	// +optional
+	UID types.UID `json:"uid,omitempty" protobuf:"bytes,3,opt,name=uid,casttype=k8s.io/apimachinery/pkg/types.UID"`
+}
+
+// BucketStatus is the status of the PXBucket
+type BucketStatus struct {
+	// phase is the binding phase of the PXBucket
+	// +optional
+	Phase BucketBindingPhase `json:"phase,omitempty" protobuf:"bytes,1,opt,name=phase"`
+
+	// lastTransitionTime is the last time the phase changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,2,opt,name=lastTransitionTime"`
+}
+
+// BucketBindingPhase describes the binding phase of a PXBucket
+type BucketBindingPhase string
+
+const (
+	// BucketAvailable means the PXBucket is not bound and can be claimed
+	BucketAvailable BucketBindingPhase = "Available"
+
+	// BucketBound means the PXBucket is bound to a PXBucketClaim
+	BucketBound BucketBindingPhase = "Bound"
+
+	// BucketReleased means the bound PXBucketClaim was deleted and the bucket was retained.
+	// It can be claimed again from the namespace of its last claim.
+	BucketReleased BucketBindingPhase = "Released"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// PXBucketList is a list of PXBucket objects
+type PXBucketList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// List of PXBuckets
+	Items []PXBucket `json:"items" protobuf:"bytes,2,rep,name=items"`
+}
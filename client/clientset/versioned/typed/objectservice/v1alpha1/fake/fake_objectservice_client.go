@@ -12,10 +12,18 @@ type FakeObjectV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeObjectV1alpha1) PXBuckets() v1alpha1.PXBucketInterface {
+	return &FakePXBuckets{c}
+}
+
 func (c *FakeObjectV1alpha1) PXBucketAccesses(namespace string) v1alpha1.PXBucketAccessInterface {
 	return &FakePXBucketAccesses{c, namespace}
 }
 
+func (c *FakeObjectV1alpha1) PXBucketAccessClasses() v1alpha1.PXBucketAccessClassInterface {
+	return &FakePXBucketAccessClasses{c}
+}
+
 func (c *FakeObjectV1alpha1) PXBucketClaims(namespace string) v1alpha1.PXBucketClaimInterface {
 	return &FakePXBucketClaims{c, namespace}
 }
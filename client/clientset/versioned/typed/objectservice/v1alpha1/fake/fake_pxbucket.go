@@ -0,0 +1,117 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakePXBuckets implements PXBucketInterface
+type FakePXBuckets struct {
+	Fake *FakeObjectV1alpha1
+}
+
+var pxbucketsResource = schema.GroupVersionResource{Group: "object.portworx.io", Version: "v1alpha1", Resource: "pxbuckets"}
+
+var pxbucketsKind = schema.GroupVersionKind{Group: "object.portworx.io", Version: "v1alpha1", Kind: "PXBucket"}
+
+// Get takes name of the pXBucket, and returns the corresponding pXBucket object, and an error if there is any.
+func (c *FakePXBuckets) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.PXBucket, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(pxbucketsResource, name), &v1alpha1.PXBucket{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucket), err
+}
+
+// List takes label and field selectors, and returns the list of PXBuckets that match those selectors.
+func (c *FakePXBuckets) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.PXBucketList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(pxbucketsResource, pxbucketsKind, opts), &v1alpha1.PXBucketList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.PXBucketList{ListMeta: obj.(*v1alpha1.PXBucketList).ListMeta}
+	for _, item := range obj.(*v1alpha1.PXBucketList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested pXBuckets.
+func (c *FakePXBuckets) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(pxbucketsResource, opts))
+}
+
+// Create takes the representation of a pXBucket and creates it.  Returns the server's representation of the pXBucket, and an error, if there is any.
+func (c *FakePXBuckets) Create(ctx context.Context, pXBucket *v1alpha1.PXBucket, opts v1.CreateOptions) (result *v1alpha1.PXBucket, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(pxbucketsResource, pXBucket), &v1alpha1.PXBucket{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucket), err
+}
+
+// Update takes the representation of a pXBucket and updates it. Returns the server's representation of the pXBucket, and an error, if there is any.
+func (c *FakePXBuckets) Update(ctx context.Context, pXBucket *v1alpha1.PXBucket, opts v1.UpdateOptions) (result *v1alpha1.PXBucket, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(pxbucketsResource, pXBucket), &v1alpha1.PXBucket{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucket), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakePXBuckets) UpdateStatus(ctx context.Context, pXBucket *v1alpha1.PXBucket, opts v1.UpdateOptions) (*v1alpha1.PXBucket, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(pxbucketsResource, "status", pXBucket), &v1alpha1.PXBucket{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucket), err
+}
+
+// Delete takes name of the pXBucket and deletes it. Returns an error if one occurs.
+func (c *FakePXBuckets) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(pxbucketsResource, name), &v1alpha1.PXBucket{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakePXBuckets) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(pxbucketsResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.PXBucketList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched pXBucket.
+func (c *FakePXBuckets) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.PXBucket, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(pxbucketsResource, name, pt, data, subresources...), &v1alpha1.PXBucket{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucket), err
+}
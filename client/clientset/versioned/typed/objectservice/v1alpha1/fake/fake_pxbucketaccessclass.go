@@ -0,0 +1,106 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakePXBucketAccessClasses implements PXBucketAccessClassInterface
+type FakePXBucketAccessClasses struct {
+	Fake *FakeObjectV1alpha1
+}
+
+var pxbucketaccessclassesResource = schema.GroupVersionResource{Group: "object.portworx.io", Version: "v1alpha1", Resource: "pxbucketaccessclasses"}
+
+var pxbucketaccessclassesKind = schema.GroupVersionKind{Group: "object.portworx.io", Version: "v1alpha1", Kind: "PXBucketAccessClass"}
+
+// Get takes name of the pXBucketAccessClass, and returns the corresponding pXBucketAccessClass object, and an error if there is any.
+func (c *FakePXBucketAccessClasses) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.PXBucketAccessClass, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(pxbucketaccessclassesResource, name), &v1alpha1.PXBucketAccessClass{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucketAccessClass), err
+}
+
+// List takes label and field selectors, and returns the list of PXBucketAccessClasses that match those selectors.
+func (c *FakePXBucketAccessClasses) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.PXBucketAccessClassList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(pxbucketaccessclassesResource, pxbucketaccessclassesKind, opts), &v1alpha1.PXBucketAccessClassList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.PXBucketAccessClassList{ListMeta: obj.(*v1alpha1.PXBucketAccessClassList).ListMeta}
+	for _, item := range obj.(*v1alpha1.PXBucketAccessClassList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested pXBucketAccessClasses.
+func (c *FakePXBucketAccessClasses) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(pxbucketaccessclassesResource, opts))
+}
+
+// Create takes the representation of a pXBucketAccessClass and creates it.  Returns the server's representation of the pXBucketAccessClass, and an error, if there is any.
+func (c *FakePXBucketAccessClasses) Create(ctx context.Context, pXBucketAccessClass *v1alpha1.PXBucketAccessClass, opts v1.CreateOptions) (result *v1alpha1.PXBucketAccessClass, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(pxbucketaccessclassesResource, pXBucketAccessClass), &v1alpha1.PXBucketAccessClass{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucketAccessClass), err
+}
+
+// Update takes the representation of a pXBucketAccessClass and updates it. Returns the server's representation of the pXBucketAccessClass, and an error, if there is any.
+func (c *FakePXBucketAccessClasses) Update(ctx context.Context, pXBucketAccessClass *v1alpha1.PXBucketAccessClass, opts v1.UpdateOptions) (result *v1alpha1.PXBucketAccessClass, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(pxbucketaccessclassesResource, pXBucketAccessClass), &v1alpha1.PXBucketAccessClass{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucketAccessClass), err
+}
+
+// Delete takes name of the pXBucketAccessClass and deletes it. Returns an error if one occurs.
+func (c *FakePXBucketAccessClasses) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(pxbucketaccessclassesResource, name), &v1alpha1.PXBucketAccessClass{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakePXBucketAccessClasses) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(pxbucketaccessclassesResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.PXBucketAccessClassList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched pXBucketAccessClass.
+func (c *FakePXBucketAccessClasses) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.PXBucketAccessClass, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(pxbucketaccessclassesResource, name, pt, data, subresources...), &v1alpha1.PXBucketAccessClass{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.PXBucketAccessClass), err
+}
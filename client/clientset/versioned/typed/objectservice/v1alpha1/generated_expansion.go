@@ -2,8 +2,12 @@
 
 package v1alpha1
 
+type PXBucketExpansion interface{}
+
 type PXBucketAccessExpansion interface{}
 
+type PXBucketAccessClassExpansion interface{}
+
 type PXBucketClaimExpansion interface{}
 
 type PXBucketClassExpansion interface{}
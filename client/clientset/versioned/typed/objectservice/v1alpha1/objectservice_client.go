@@ -10,7 +10,9 @@ import (
 
 type ObjectV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	PXBucketsGetter
 	PXBucketAccessesGetter
+	PXBucketAccessClassesGetter
 	PXBucketClaimsGetter
 	PXBucketClassesGetter
 }
@@ -20,10 +22,18 @@ type ObjectV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ObjectV1alpha1Client) PXBuckets() PXBucketInterface {
+	return newPXBuckets(c)
+}
+
 func (c *ObjectV1alpha1Client) PXBucketAccesses(namespace string) PXBucketAccessInterface {
 	return newPXBucketAccesses(c, namespace)
 }
 
+func (c *ObjectV1alpha1Client) PXBucketAccessClasses() PXBucketAccessClassInterface {
+	return newPXBucketAccessClasses(c)
+}
+
 func (c *ObjectV1alpha1Client) PXBucketClaims(namespace string) PXBucketClaimInterface {
 	return newPXBucketClaims(c, namespace)
 }
@@ -37,8 +37,12 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=object.portworx.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("pxbuckets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Object().V1alpha1().PXBuckets().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("pxbucketaccesses"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Object().V1alpha1().PXBucketAccesses().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("pxbucketaccessclasses"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Object().V1alpha1().PXBucketAccessClasses().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("pxbucketclaims"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Object().V1alpha1().PXBucketClaims().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("pxbucketclasses"):
@@ -8,8 +8,12 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// PXBuckets returns a PXBucketInformer.
+	PXBuckets() PXBucketInformer
 	// PXBucketAccesses returns a PXBucketAccessInformer.
 	PXBucketAccesses() PXBucketAccessInformer
+	// PXBucketAccessClasses returns a PXBucketAccessClassInformer.
+	PXBucketAccessClasses() PXBucketAccessClassInformer
 	// PXBucketClaims returns a PXBucketClaimInformer.
 	PXBucketClaims() PXBucketClaimInformer
 	// PXBucketClasses returns a PXBucketClassInformer.
@@ -27,11 +31,21 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// PXBuckets returns a PXBucketInformer.
+func (v *version) PXBuckets() PXBucketInformer {
+	return &pXBucketInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // PXBucketAccesses returns a PXBucketAccessInformer.
 func (v *version) PXBucketAccesses() PXBucketAccessInformer {
 	return &pXBucketAccessInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// PXBucketAccessClasses returns a PXBucketAccessClassInformer.
+func (v *version) PXBucketAccessClasses() PXBucketAccessClassInformer {
+	return &pXBucketAccessClassInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // PXBucketClaims returns a PXBucketClaimInformer.
 func (v *version) PXBucketClaims() PXBucketClaimInformer {
 	return &pXBucketClaimInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,73 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	objectservicev1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	versioned "github.com/portworx/px-object-controller/client/clientset/versioned"
+	internalinterfaces "github.com/portworx/px-object-controller/client/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/portworx/px-object-controller/client/listers/objectservice/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// PXBucketInformer provides access to a shared informer and lister for
+// PXBuckets.
+type PXBucketInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.PXBucketLister
+}
+
+type pXBucketInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewPXBucketInformer constructs a new informer for PXBucket type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewPXBucketInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredPXBucketInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredPXBucketInformer constructs a new informer for PXBucket type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredPXBucketInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ObjectV1alpha1().PXBuckets().List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ObjectV1alpha1().PXBuckets().Watch(context.TODO(), options)
+			},
+		},
+		&objectservicev1alpha1.PXBucket{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *pXBucketInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredPXBucketInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *pXBucketInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&objectservicev1alpha1.PXBucket{}, f.defaultInformer)
+}
+
+func (f *pXBucketInformer) Lister() v1alpha1.PXBucketLister {
+	return v1alpha1.NewPXBucketLister(f.Informer().GetIndexer())
+}
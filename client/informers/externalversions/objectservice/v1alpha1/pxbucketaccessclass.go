@@ -0,0 +1,73 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	objectservicev1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	versioned "github.com/portworx/px-object-controller/client/clientset/versioned"
+	internalinterfaces "github.com/portworx/px-object-controller/client/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/portworx/px-object-controller/client/listers/objectservice/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// PXBucketAccessClassInformer provides access to a shared informer and lister for
+// PXBucketAccessClasses.
+type PXBucketAccessClassInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.PXBucketAccessClassLister
+}
+
+type pXBucketAccessClassInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewPXBucketAccessClassInformer constructs a new informer for PXBucketAccessClass type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewPXBucketAccessClassInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredPXBucketAccessClassInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredPXBucketAccessClassInformer constructs a new informer for PXBucketAccessClass type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredPXBucketAccessClassInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ObjectV1alpha1().PXBucketAccessClasses().List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.ObjectV1alpha1().PXBucketAccessClasses().Watch(context.TODO(), options)
+			},
+		},
+		&objectservicev1alpha1.PXBucketAccessClass{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *pXBucketAccessClassInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredPXBucketAccessClassInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *pXBucketAccessClassInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&objectservicev1alpha1.PXBucketAccessClass{}, f.defaultInformer)
+}
+
+func (f *pXBucketAccessClassInformer) Lister() v1alpha1.PXBucketAccessClassLister {
+	return v1alpha1.NewPXBucketAccessClassLister(f.Informer().GetIndexer())
+}
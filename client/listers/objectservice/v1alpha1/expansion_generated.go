@@ -2,6 +2,10 @@
 
 package v1alpha1
 
+// PXBucketListerExpansion allows custom methods to be added to
+// PXBucketLister.
+type PXBucketListerExpansion interface{}
+
 // PXBucketAccessListerExpansion allows custom methods to be added to
 // PXBucketAccessLister.
 type PXBucketAccessListerExpansion interface{}
@@ -10,6 +14,10 @@ type PXBucketAccessListerExpansion interface{}
 // PXBucketAccessNamespaceLister.
 type PXBucketAccessNamespaceListerExpansion interface{}
 
+// PXBucketAccessClassListerExpansion allows custom methods to be added to
+// PXBucketAccessClassLister.
+type PXBucketAccessClassListerExpansion interface{}
+
 // PXBucketClaimListerExpansion allows custom methods to be added to
 // PXBucketClaimLister.
 type PXBucketClaimListerExpansion interface{}
@@ -0,0 +1,52 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PXBucketLister helps list PXBuckets.
+// All objects returned here must be treated as read-only.
+type PXBucketLister interface {
+	// List lists all PXBuckets in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.PXBucket, err error)
+	// Get retrieves the PXBucket from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.PXBucket, error)
+	PXBucketListerExpansion
+}
+
+// pXBucketLister implements the PXBucketLister interface.
+type pXBucketLister struct {
+	indexer cache.Indexer
+}
+
+// NewPXBucketLister returns a new PXBucketLister.
+func NewPXBucketLister(indexer cache.Indexer) PXBucketLister {
+	return &pXBucketLister{indexer: indexer}
+}
+
+// List lists all PXBuckets in the indexer.
+func (s *pXBucketLister) List(selector labels.Selector) (ret []*v1alpha1.PXBucket, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.PXBucket))
+	})
+	return ret, err
+}
+
+// Get retrieves the PXBucket from the index for a given name.
+func (s *pXBucketLister) Get(name string) (*v1alpha1.PXBucket, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("pxbucket"), name)
+	}
+	return obj.(*v1alpha1.PXBucket), nil
+}
@@ -0,0 +1,52 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PXBucketAccessClassLister helps list PXBucketAccessClasses.
+// All objects returned here must be treated as read-only.
+type PXBucketAccessClassLister interface {
+	// List lists all PXBucketAccessClasses in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.PXBucketAccessClass, err error)
+	// Get retrieves the PXBucketAccessClass from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.PXBucketAccessClass, error)
+	PXBucketAccessClassListerExpansion
+}
+
+// pXBucketAccessClassLister implements the PXBucketAccessClassLister interface.
+type pXBucketAccessClassLister struct {
+	indexer cache.Indexer
+}
+
+// NewPXBucketAccessClassLister returns a new PXBucketAccessClassLister.
+func NewPXBucketAccessClassLister(indexer cache.Indexer) PXBucketAccessClassLister {
+	return &pXBucketAccessClassLister{indexer: indexer}
+}
+
+// List lists all PXBucketAccessClasses in the indexer.
+func (s *pXBucketAccessClassLister) List(selector labels.Selector) (ret []*v1alpha1.PXBucketAccessClass, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.PXBucketAccessClass))
+	})
+	return ret, err
+}
+
+// Get retrieves the PXBucketAccessClass from the index for a given name.
+func (s *pXBucketAccessClassLister) Get(name string) (*v1alpha1.PXBucketAccessClass, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("pxbucketaccessclass"), name)
+	}
+	return obj.(*v1alpha1.PXBucketAccessClass), nil
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -19,17 +21,23 @@ import (
 	"github.com/libopenstorage/openstorage/pkg/correlation"
 	"github.com/libopenstorage/openstorage/pkg/storagepolicy"
 	"github.com/portworx/kvdb"
+	"github.com/portworx/px-object-controller/pkg/backend"
 	"github.com/portworx/px-object-controller/pkg/controller"
+	"github.com/portworx/px-object-controller/pkg/health"
+	"github.com/portworx/px-object-controller/pkg/kubeconfig"
+	"github.com/portworx/px-object-controller/pkg/metrics"
+	"github.com/portworx/px-object-controller/pkg/tracing"
 	"github.com/portworx/px-object-controller/pkg/version"
+	"github.com/portworx/px-object-controller/pkg/webhook"
 	"github.com/sirupsen/logrus"
 	"github.com/zoido/yag-config"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/klog/v2"
 )
 
 const (
 	envKubeconfig                  = "KUBECONFIG"
+	envKubeAPIQPS                  = "KUBE_API_QPS"
+	envKubeAPIBurst                = "KUBE_API_BURST"
 	envLogLevel                    = "LOG_LEVEL"
 	envNamespace                   = "NAMESPACE"
 	envWorkerThreads               = "WORKER_THREADS"
@@ -38,6 +46,7 @@ const (
 	envLeaderElectionLeaseDuration = "ENABLE_LEADER_ELECTION_LEASE_DURATION"
 	envLeaderElectionRenewDeadline = "ENABLE_LEADER_ELECTION_RENEW_DEADLINE"
 	envLeaderElectionRetryPeriod   = "ENABLE_LEADER_ELECTION_RETRY_PERIOD"
+	envLeaderElectionHealthTimeout = "ENABLE_LEADER_ELECTION_HEALTH_CHECK_TIMEOUT"
 	envSDKPort                     = "SDK_PORT"
 	envRestPort                    = "REST_PORT"
 	envBucketDriver                = "BUCKET_DRIVER"
@@ -49,10 +58,25 @@ const (
 	envPureFBAdminAccessKeyID      = "PURE_FB_ADMIN_ACCESS_KEY_ID"
 	envPureFBAdminSecretAccessKey  = "PURE_FB_ADMIN_SECRET_ACCESS_KEY"
 	envSdkEndpoint                 = "SDK_ENDPOINT"
+	envEnableWebhook               = "ENABLE_WEBHOOK"
+	envWebhookPort                 = "WEBHOOK_PORT"
+	envWebhookCertFile             = "WEBHOOK_CERT_FILE"
+	envWebhookKeyFile              = "WEBHOOK_KEY_FILE"
+	envMetricsPort                 = "METRICS_PORT"
+	envGCMode                      = "GC_MODE"
+	envGCInterval                  = "GC_INTERVAL"
+	envGCGracePeriod               = "GC_GRACE_PERIOD"
+	envOTLPEndpoint                = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTelServiceName             = "OTEL_SERVICE_NAME"
+
+	// tracingShutdownTimeout bounds the export of the spans left at exit
+	tracingShutdownTimeout = 5 * time.Second
 )
 
 var (
-	kubeconfig                  string
+	kubeconfigPath              string
+	kubeAPIQPS                  = 5
+	kubeAPIBurst                = 10
 	controllerNamespace         = "kube-system"
 	logLevel                    = "debug"
 	workers                     = 4
@@ -61,6 +85,7 @@ var (
 	leaderElectionLeaseDuration = 15 * time.Second
 	leaderElectionRenewDeadline = 10 * time.Second
 	leaderElectionRetryPeriod   = 5 * time.Second
+	leaderElectionHealthTimeout = 20 * time.Second
 	sdkPort                     = "18020"
 	restPort                    = "18021"
 	resyncPeriod                = 15 * time.Minute
@@ -71,12 +96,24 @@ var (
 	pureFBAccessKeyID           = ""
 	pureFBSecretAccessKey       = ""
 	sdkEndpoint                 = ""
+	enableWebhook               = false
+	webhookPort                 = "8443"
+	webhookCertFile             = "/etc/px-object-controller/webhook/tls.crt"
+	webhookKeyFile              = "/etc/px-object-controller/webhook/tls.key"
+	metricsPort                 = "9090"
+	gcMode                      = controller.GCModeDisabled
+	gcInterval                  = 1 * time.Hour
+	gcGracePeriod               = 24 * time.Hour
+	otlpEndpoint                = ""
+	otelServiceName             = "px-object-controller"
 )
 
 func parseFlags() error {
 	y := yag.New()
 
-	y.String(&kubeconfig, envKubeconfig, "Absolute path to the kubeconfig file. Required only when running out of cluster.")
+	y.String(&kubeconfigPath, envKubeconfig, "Absolute path to the kubeconfig file. Defaults to the in-cluster config, then to the default kubeconfig when running out of cluster.")
+	y.Int(&kubeAPIQPS, envKubeAPIQPS, "Maximum queries per second of each client to the Kubernetes API server. Defaults to 5.")
+	y.Int(&kubeAPIBurst, envKubeAPIBurst, "Maximum burst of queries of each client to the Kubernetes API server. Defaults to 10.")
 	y.String(&controllerNamespace, envNamespace, "The namespace where the controller is running. Defaults to kube-system")
 	y.String(&logLevel, envLogLevel, "Log level to use. Defaults to debug.")
 	y.Bool(&leaderElection, envEnableLeaderElection, "Enables leader election.")
@@ -84,6 +121,7 @@ func parseFlags() error {
 	y.Duration(&leaderElectionLeaseDuration, envLeaderElectionLeaseDuration, "Duration, in seconds, that non-leader candidates will wait to force acquire leadership. Defaults to 15 seconds.")
 	y.Duration(&leaderElectionRenewDeadline, envLeaderElectionRenewDeadline, "Duration, in seconds, that the acting leader will retry refreshing leadership before giving up. Defaults to 10 seconds.")
 	y.Duration(&leaderElectionRetryPeriod, envLeaderElectionRetryPeriod, "Duration, in seconds, the LeaderElector clients should wait between tries of actions. Defaults to 5 seconds.")
+	y.Duration(&leaderElectionHealthTimeout, envLeaderElectionHealthTimeout, "Duration, in seconds, the leader may fail to renew its lease beyond its expiration before the liveness check fails. Defaults to 20 seconds.")
 
 	y.Int(&workers, envWorkerThreads, "Number of worker threads.")
 	y.String(&sdkPort, envSDKPort, "Openstorage SDK server port")
@@ -96,6 +134,16 @@ func parseFlags() error {
 	y.String(&pureFBAccessKeyID, envPureFBAdminAccessKeyID, "Openstorage Pure FB Bucket Driver Access Key ID")
 	y.String(&pureFBSecretAccessKey, envPureFBAdminSecretAccessKey, "Openstorage Pure FB Bucket Driver Access Secret Key")
 	y.String(&sdkEndpoint, envSdkEndpoint, "Openstorage SDK Endpoint")
+	y.Bool(&enableWebhook, envEnableWebhook, "Enables the admission webhook server. Defaults to false.")
+	y.String(&webhookPort, envWebhookPort, "Admission webhook server port. Defaults to 8443.")
+	y.String(&webhookCertFile, envWebhookCertFile, "Path to the TLS certificate of the admission webhook server.")
+	y.String(&webhookKeyFile, envWebhookKeyFile, "Path to the TLS key of the admission webhook server.")
+	y.String(&metricsPort, envMetricsPort, "Port of the metrics and health check server. Set to an empty value to disable it. Defaults to 9090.")
+	y.String(&gcMode, envGCMode, "Garbage collection of orphaned buckets and accounts: disabled, report or delete. Defaults to disabled.")
+	y.Duration(&gcInterval, envGCInterval, "Interval of the garbage collection of orphaned buckets and accounts. Defaults to 1 hour.")
+	y.Duration(&gcGracePeriod, envGCGracePeriod, "Minimum age of orphaned buckets and accounts before they are deleted. Defaults to 24 hours.")
+	y.String(&otlpEndpoint, envOTLPEndpoint, "Base URL of the OTLP/HTTP collector to export traces to, such as http://otel-collector:4318. Tracing is disabled if empty.")
+	y.String(&otelServiceName, envOTelServiceName, "Service name of the exported traces. Defaults to px-object-controller.")
 
 	return y.ParseEnv()
 }
@@ -120,13 +168,21 @@ func main() {
 		logrus.Fatalf("failed to initialize billing sink: %v", err)
 	}
 
+	if err := tracing.Init(&tracing.Config{
+		Endpoint:    otlpEndpoint,
+		ServiceName: otelServiceName,
+	}); err != nil {
+		logrus.Fatalf("failed to initialize tracing: %v", err)
+	}
+
 	// No endpoint provided, let's start the SDK server locally.
 	// Otherwise, target SDK cluster.
 	driversMap := make(map[string]bucket.BucketDriver)
+	checker := health.NewChecker()
 	if sdkEndpoint == "" {
 		// Create and start bucket drivers
 		fakeBucketDriver := fake.New()
-		driversMap[fakeBucketDriver.String()] = fakeBucketDriver
+		driversMap[fakeBucketDriver.String()] = tracing.WrapBucketDriver(fakeBucketDriver)
 		go func() {
 			if err := fakeBucketDriver.Start(); err != http.ErrServerClosed {
 				logrus.Errorf("failed to start driver %s: %v", fakeBucketDriver.String(), err)
@@ -139,7 +195,7 @@ func main() {
 		if err != nil {
 			logrus.Fatalf("failed to create new s3 driver: %v", err)
 		}
-		driversMap[s3Driver.String()] = s3Driver
+		driversMap[s3Driver.String()] = tracing.WrapBucketDriver(s3Driver)
 		pureFBConfig := &aws.Config{
 			Credentials: credentials.NewStaticCredentials(pureFBAccessKeyID, pureFBSecretAccessKey, ""),
 		}
@@ -148,7 +204,7 @@ func main() {
 		if err != nil {
 			logrus.Fatalf("failed to create new s3 driver: %v", err)
 		}
-		driversMap[pureFBDriver.String()] = pureFBDriver
+		driversMap[pureFBDriver.String()] = tracing.WrapBucketDriver(pureFBDriver)
 
 		// Create SDK object and start in background
 		u, err := url.Parse("kv-mem://localhost")
@@ -182,33 +238,128 @@ func main() {
 			logrus.Fatalf("failed to start SDK server for driver: %v", err)
 		}
 		sdkServer.UseBucketDrivers(driversMap)
-		go sdkServer.Start()
+		go func() {
+			if err := sdkServer.Start(); err != nil {
+				logrus.Errorf("failed to start SDK server: %v", err)
+			}
+		}()
+
+		// The embedded SDK server is restarted with the pod
+		socket := sdkEndpoint
+		checker.AddLivenessCheck("sdk-server", func(ctx context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "unix", socket)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		})
 	} else {
 		logrus.Infof("Skipping SDK server startup, connecting to %v instead", sdkEndpoint)
 	}
 
+	// Shared by the controller, the webhook and the leader election clients
+	kubeConfig, err := kubeconfig.Load(&kubeconfig.Config{
+		Kubeconfig: kubeconfigPath,
+		QPS:        float32(kubeAPIQPS),
+		Burst:      kubeAPIBurst,
+	})
+	if err != nil {
+		logrus.Fatalf("failed to load kubernetes client config: %v", err)
+	}
+
+	// Direct backend clients for operations the SDK does not provide
+	backends := make(map[string]*backend.Client)
+	if s3AccessKeyID != "" {
+		s3Backend, err := backend.New(&aws.Config{
+			Credentials: credentials.NewStaticCredentials(s3AccessKeyID, s3SecretAccessKey, ""),
+		})
+		if err != nil {
+			logrus.Fatalf("failed to create s3 backend client: %v", err)
+		}
+		backends["S3Driver"] = s3Backend
+	}
+	if pureFBAccessKeyID != "" {
+		pureFBBackend, err := backend.New((&aws.Config{
+			Credentials: credentials.NewStaticCredentials(pureFBAccessKeyID, pureFBSecretAccessKey, ""),
+		}).WithDisableSSL(true).WithS3ForcePathStyle(true))
+		if err != nil {
+			logrus.Fatalf("failed to create pure fb backend client: %v", err)
+		}
+		backends["PureFBDriver"] = pureFBBackend
+	}
+
 	// Create controller object
 	ctrl, err := controller.New(&controller.Config{
+		KubeConfig:         kubeConfig,
 		SdkEndpoint:        sdkEndpoint,
+		ResyncPeriod:       resyncPeriod,
 		RetryIntervalStart: retryIntervalStart,
 		RetryIntervalMax:   retryIntervalMax,
+		Backends:           backends,
+		Namespace:          controllerNamespace,
+		GCMode:             gcMode,
+		GCInterval:         gcInterval,
+		GCGracePeriod:      gcGracePeriod,
 	})
 	if err != nil {
 		logrus.Error(err.Error())
 		os.Exit(1)
 	}
+	ctrl.AddHealthChecks(checker)
+
+	// The webhook is served by every replica, not only by the leader
+	if enableWebhook {
+		webhookServer, err := webhook.New(&webhook.Config{
+			KubeConfig: kubeConfig,
+			Port:       webhookPort,
+			CertFile:   webhookCertFile,
+			KeyFile:    webhookKeyFile,
+		})
+		if err != nil {
+			logrus.Fatalf("failed to create webhook server: %v", err)
+		}
+		go func() {
+			if err := webhookServer.Run(make(chan struct{})); err != nil {
+				logrus.Fatalf("failed to run webhook server: %v", err)
+			}
+		}()
+	}
+
+	// Metrics and health checks are served by every replica, not only by the leader
+	if metricsPort != "" {
+		metricsServer := metrics.New(&metrics.Config{
+			Port: metricsPort,
+		})
+		metricsServer.Handle(health.LivenessPath, checker.LivenessHandler())
+		metricsServer.Handle(health.ReadinessPath, checker.ReadinessHandler())
+		go func() {
+			if err := metricsServer.Run(make(chan struct{})); err != nil {
+				logrus.Fatalf("failed to run metrics server: %v", err)
+			}
+		}()
+	}
 
 	// Callback to start controller & sdk in goroutine
 	run := func(context.Context) {
+		metrics.SetLeader(true)
+
 		// Run controller
 		stopCh := make(chan struct{})
 		go ctrl.Run(workers, stopCh)
 
-		// Until SIGINT
+		// Until SIGINT or SIGTERM
 		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 		<-c
 		close(stopCh)
+
+		// Export the spans of the last reconciles before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := tracing.Shutdown(ctx); err != nil {
+			logrus.Errorf("failed to shut down tracing: %v", err)
+		}
 	}
 
 	// Start main loop with leader election
@@ -219,11 +370,7 @@ func main() {
 		lockName := "px-object-controller-leader"
 		// Create a new clientset for leader election to prevent throttling
 		// due to px controller
-		config, err := rest.InClusterConfig()
-		if err != nil {
-			klog.Fatalf("failed to get in cluster config: %v", err)
-		}
-		leClientset, err := kubernetes.NewForConfig(config)
+		leClientset, err := kubernetes.NewForConfig(kubeConfig)
 		if err != nil {
 			logrus.Fatalf("failed to create leaderelection client: %v", err)
 		}
@@ -235,6 +382,8 @@ func main() {
 		le.WithLeaseDuration(leaderElectionLeaseDuration)
 		le.WithRenewDeadline(leaderElectionRenewDeadline)
 		le.WithRetryPeriod(leaderElectionRetryPeriod)
+		// Fails the liveness check of a leader that cannot renew its lease
+		le.PrepareHealthCheck(checker, leaderElectionHealthTimeout)
 		if err := le.Run(); err != nil {
 			logrus.Fatalf("failed to initialize leader election: %v", err)
 		}
@@ -0,0 +1,494 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+)
+
+var (
+	logrus = correlation.NewPackageLogger("pkg/backend")
+)
+
+// Client talks to a bucket backend directly for operations that are not
+// part of the Openstorage SDK bucket API, using the admin credentials of
+// the backend.
+type Client struct {
+	config *aws.Config
+}
+
+// New returns a new backend client for the given S3 compatible configuration
+func New(config *aws.Config) (*Client, error) {
+	if config == nil {
+		return nil, fmt.Errorf("must provide backend config")
+	}
+
+	return &Client{
+		config: config.Copy(),
+	}, nil
+}
+
+// newS3Svc returns a new S3 service client for the region and endpoint
+func (c *Client) newS3Svc(region, endpoint string) (*s3.S3, error) {
+	s3Config := c.config.Copy()
+	if region != "" {
+		s3Config.Region = aws.String(region)
+	}
+	if endpoint != "" {
+		s3Config.Endpoint = aws.String(endpoint)
+	}
+
+	sess, err := session.NewSession(s3Config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create S3 session: %v", err)
+	}
+	return s3.New(sess), nil
+}
+
+// newIamSvc returns a new IAM service client
+func (c *Client) newIamSvc() (*iam.IAM, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: c.config.Credentials,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create IAM session: %v", err)
+	}
+	return iam.New(sess), nil
+}
+
+// BucketExists returns true if the bucket exists on the backend and is
+// accessible with the admin credentials.
+func (c *Client) BucketExists(ctx context.Context, name, region, endpoint string) (bool, error) {
+	svc, err := c.newS3Svc(region, endpoint)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = svc.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(name),
+	})
+	if err != nil {
+		if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+			return false, nil
+		}
+		return false, err
+	}
+
+	logrus.WithContext(ctx).Infof("bucket %s exists", name)
+	return true, nil
+}
+
+// anonymousAccessSid identifies the bucket policy statement owned by the controller
+const anonymousAccessSid = "PXObjectControllerAnonymousAccess"
+
+// SetAnonymousAccessMode sets the anonymous access statement in the policy of
+// an existing bucket. Only the statement owned by the controller, or the
+// unnamed one applied by the S3 driver on creation, is added, replaced or
+// removed. Other statements of the policy are kept.
+func (c *Client) SetAnonymousAccessMode(ctx context.Context, name, region, endpoint string, mode api.AnonymousBucketAccessMode) error {
+	svc, err := c.newS3Svc(region, endpoint)
+	if err != nil {
+		return err
+	}
+
+	var actions []string
+	switch mode {
+	case api.AnonymousBucketAccessMode_ReadOnly:
+		actions = []string{"s3:GetObject"}
+	case api.AnonymousBucketAccessMode_WriteOnly:
+		actions = []string{"s3:PutObject"}
+	case api.AnonymousBucketAccessMode_ReadWrite:
+		actions = []string{"s3:GetObject", "s3:PutObject"}
+	}
+
+	policy := make(map[string]interface{})
+	out, err := svc.GetBucketPolicyWithContext(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(name),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchBucketPolicy" {
+			return fmt.Errorf("failed to get policy of bucket %s: %v", name, err)
+		}
+	} else if err := json.Unmarshal([]byte(aws.StringValue(out.Policy)), &policy); err != nil {
+		return fmt.Errorf("failed to parse policy of bucket %s: %v", name, err)
+	}
+
+	// A policy with a single statement may store it as an object
+	var statements []interface{}
+	switch s := policy["Statement"].(type) {
+	case []interface{}:
+		statements = s
+	case map[string]interface{}:
+		statements = []interface{}{s}
+	}
+	kept := make([]interface{}, 0, len(statements)+1)
+	for _, statement := range statements {
+		if !isAnonymousAccessStatement(statement, name) {
+			kept = append(kept, statement)
+		}
+	}
+
+	if len(actions) == 0 {
+		if len(kept) == len(statements) {
+			// The controller has no statement to remove
+			return nil
+		}
+		if len(kept) == 0 {
+			_, err = svc.DeleteBucketPolicyWithContext(ctx, &s3.DeleteBucketPolicyInput{
+				Bucket: aws.String(name),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove anonymous access policy of bucket %s: %v", name, err)
+			}
+			logrus.WithContext(ctx).Infof("removed anonymous access policy of bucket %s", name)
+			return nil
+		}
+	}
+
+	if len(actions) > 0 {
+		kept = append(kept, map[string]interface{}{
+			"Sid":       anonymousAccessSid,
+			"Effect":    "Allow",
+			"Principal": "*",
+			"Action":    actions,
+			"Resource": []string{
+				fmt.Sprintf("arn:aws:s3:::%s/*", name),
+			},
+		})
+	}
+	if _, ok := policy["Version"]; !ok {
+		policy["Version"] = "2012-10-17"
+	}
+	policy["Statement"] = kept
+	updated, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutBucketPolicyWithContext(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(name),
+		Policy: aws.String(string(updated)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set anonymous access policy of bucket %s: %v", name, err)
+	}
+
+	logrus.WithContext(ctx).Infof("set anonymous access mode %s on bucket %s", mode, name)
+	return nil
+}
+
+// isAnonymousAccessStatement returns true if the bucket policy statement grants
+// anonymous access on behalf of the controller. These are the statements with
+// the controller Sid and the unnamed statement the S3 driver applies on creation.
+func isAnonymousAccessStatement(statement interface{}, name string) bool {
+	s, ok := statement.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if sid, _ := s["Sid"].(string); sid != "" {
+		return sid == anonymousAccessSid
+	}
+	if s["Effect"] != "Allow" || s["Principal"] != "*" || len(s) != 4 {
+		return false
+	}
+	resource := fmt.Sprintf("arn:aws:s3:::%s/*", name)
+	if resources, ok := s["Resource"].([]interface{}); !ok || len(resources) != 1 || resources[0] != resource {
+		return false
+	}
+	actions, ok := s["Action"].([]interface{})
+	if !ok || len(actions) == 0 {
+		return false
+	}
+	for _, action := range actions {
+		if action != "s3:GetObject" && action != "s3:PutObject" {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateAccessKey creates a new access key for the IAM user of a bucket access account
+func (c *Client) CreateAccessKey(ctx context.Context, userName string) (string, string, error) {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return "", "", err
+	}
+
+	out, err := svc.CreateAccessKeyWithContext(ctx, &iam.CreateAccessKeyInput{
+		UserName: aws.String(userName),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create access key for user %s: %v", userName, err)
+	}
+
+	logrus.WithContext(ctx).Infof("created access key %s for user %s", aws.StringValue(out.AccessKey.AccessKeyId), userName)
+	return aws.StringValue(out.AccessKey.AccessKeyId), aws.StringValue(out.AccessKey.SecretAccessKey), nil
+}
+
+// DeleteAccessKey deletes an access key of the IAM user of a bucket access
+// account. Keys and users that no longer exist are ignored.
+func (c *Client) DeleteAccessKey(ctx context.Context, userName, accessKeyID string) error {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.DeleteAccessKeyWithContext(ctx, &iam.DeleteAccessKeyInput{
+		UserName:    aws.String(userName),
+		AccessKeyId: aws.String(accessKeyID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			logrus.WithContext(ctx).Infof("access key %s of user %s already deleted", accessKeyID, userName)
+			return nil
+		}
+		return fmt.Errorf("failed to delete access key %s of user %s: %v", accessKeyID, userName, err)
+	}
+
+	logrus.WithContext(ctx).Infof("deleted access key %s of user %s", accessKeyID, userName)
+	return nil
+}
+
+// ListAccessKeys returns the creation times of the access keys of the IAM user
+// of a bucket access account by access key ID
+func (c *Client) ListAccessKeys(ctx context.Context, userName string) (map[string]time.Time, error) {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]time.Time)
+	err = svc.ListAccessKeysPagesWithContext(ctx, &iam.ListAccessKeysInput{
+		UserName: aws.String(userName),
+	}, func(out *iam.ListAccessKeysOutput, lastPage bool) bool {
+		for _, key := range out.AccessKeyMetadata {
+			keys[aws.StringValue(key.AccessKeyId)] = aws.TimeValue(key.CreateDate)
+		}
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			// A user that no longer exists has no keys
+			return keys, nil
+		}
+		return nil, fmt.Errorf("failed to list access keys of user %s: %v", userName, err)
+	}
+	return keys, nil
+}
+
+// BucketInfo describes a bucket listed on the backend
+type BucketInfo struct {
+	// Name is the name of the bucket
+	Name string
+	// CreationDate is the time the bucket was created
+	CreationDate time.Time
+}
+
+// ListBuckets returns the buckets owned by the admin credentials
+func (c *Client) ListBuckets(ctx context.Context, region, endpoint string) ([]BucketInfo, error) {
+	svc, err := c.newS3Svc(region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := svc.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buckets: %v", err)
+	}
+	buckets := make([]BucketInfo, 0, len(out.Buckets))
+	for _, b := range out.Buckets {
+		buckets = append(buckets, BucketInfo{
+			Name:         aws.StringValue(b.Name),
+			CreationDate: aws.TimeValue(b.CreationDate),
+		})
+	}
+	return buckets, nil
+}
+
+// ListUsers returns the creation times of the IAM users whose names start
+// with the prefix by user name
+func (c *Client) ListUsers(ctx context.Context, prefix string) (map[string]time.Time, error) {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]time.Time)
+	err = svc.ListUsersPagesWithContext(ctx, &iam.ListUsersInput{}, func(out *iam.ListUsersOutput, lastPage bool) bool {
+		for _, user := range out.Users {
+			if name := aws.StringValue(user.UserName); strings.HasPrefix(name, prefix) {
+				users[name] = aws.TimeValue(user.CreateDate)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %v", err)
+	}
+	return users, nil
+}
+
+// DeleteUser deletes the IAM user of a bucket access account together with
+// its access policies and access keys. Users that no longer exist are ignored.
+func (c *Client) DeleteUser(ctx context.Context, userName string) error {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return err
+	}
+
+	var policies []string
+	err = svc.ListUserPoliciesPagesWithContext(ctx, &iam.ListUserPoliciesInput{
+		UserName: aws.String(userName),
+	}, func(out *iam.ListUserPoliciesOutput, lastPage bool) bool {
+		policies = append(policies, aws.StringValueSlice(out.PolicyNames)...)
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			logrus.WithContext(ctx).Infof("user %s already deleted", userName)
+			return nil
+		}
+		return fmt.Errorf("failed to list policies of user %s: %v", userName, err)
+	}
+	for _, policy := range policies {
+		if _, err := svc.DeleteUserPolicyWithContext(ctx, &iam.DeleteUserPolicyInput{
+			UserName:   aws.String(userName),
+			PolicyName: aws.String(policy),
+		}); err != nil {
+			return fmt.Errorf("failed to delete policy %s of user %s: %v", policy, userName, err)
+		}
+	}
+
+	keys, err := c.ListAccessKeys(ctx, userName)
+	if err != nil {
+		return err
+	}
+	for accessKeyID := range keys {
+		if err := c.DeleteAccessKey(ctx, userName, accessKeyID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := svc.DeleteUserWithContext(ctx, &iam.DeleteUserInput{
+		UserName: aws.String(userName),
+	}); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+		return fmt.Errorf("failed to delete user %s: %v", userName, err)
+	}
+
+	logrus.WithContext(ctx).Infof("deleted user %s", userName)
+	return nil
+}
+
+// GetBucketTags returns the tags of a bucket. Buckets without tags return an
+// empty map.
+func (c *Client) GetBucketTags(ctx context.Context, name, region, endpoint string) (map[string]string, error) {
+	svc, err := c.newS3Svc(region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return getBucketTags(ctx, svc, name)
+}
+
+func getBucketTags(ctx context.Context, svc *s3.S3, name string) (map[string]string, error) {
+	tags := make(map[string]string)
+	out, err := svc.GetBucketTaggingWithContext(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(name),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchTagSet" {
+			return tags, nil
+		}
+		return nil, fmt.Errorf("failed to get tags of bucket %s: %v", name, err)
+	}
+	for _, tag := range out.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// TagBucket adds tags to a bucket. Existing tags with other keys are kept.
+func (c *Client) TagBucket(ctx context.Context, name, region, endpoint string, tags map[string]string) error {
+	svc, err := c.newS3Svc(region, endpoint)
+	if err != nil {
+		return err
+	}
+
+	merged, err := getBucketTags(ctx, svc, name)
+	if err != nil {
+		return err
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	tagSet := make([]*s3.Tag, 0, len(merged))
+	for key, value := range merged {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	_, err = svc.PutBucketTaggingWithContext(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(name),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag bucket %s: %v", name, err)
+	}
+
+	logrus.WithContext(ctx).Infof("tagged bucket %s", name)
+	return nil
+}
+
+// GetUserTags returns the tags of the IAM user of a bucket access account
+func (c *Client) GetUserTags(ctx context.Context, userName string) (map[string]string, error) {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	err = svc.ListUserTagsPagesWithContext(ctx, &iam.ListUserTagsInput{
+		UserName: aws.String(userName),
+	}, func(out *iam.ListUserTagsOutput, lastPage bool) bool {
+		for _, tag := range out.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags of user %s: %v", userName, err)
+	}
+	return tags, nil
+}
+
+// TagUser adds tags to the IAM user of a bucket access account
+func (c *Client) TagUser(ctx context.Context, userName string, tags map[string]string) error {
+	svc, err := c.newIamSvc()
+	if err != nil {
+		return err
+	}
+
+	input := &iam.TagUserInput{UserName: aws.String(userName)}
+	for key, value := range tags {
+		input.Tags = append(input.Tags, &iam.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	if _, err := svc.TagUserWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to tag user %s: %v", userName, err)
+	}
+
+	logrus.WithContext(ctx).Infof("tagged user %s", userName)
+	return nil
+}
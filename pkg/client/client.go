@@ -1,12 +1,20 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"path"
 	"sync"
+	"time"
 
+	"github.com/libopenstorage/openstorage/api/server/sdk"
 	"github.com/libopenstorage/openstorage/pkg/correlation"
 	"github.com/libopenstorage/openstorage/pkg/grpcserver"
+	"github.com/portworx/px-object-controller/pkg/metrics"
+	"github.com/portworx/px-object-controller/pkg/tracing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
 )
 
 type Client struct {
@@ -36,7 +44,11 @@ func (c *Client) getConn() (*grpc.ClientConn, error) {
 			c.cfg.SdkEndpoint,
 			[]grpc.DialOption{
 				grpc.WithInsecure(),
-				grpc.WithUnaryInterceptor(correlation.ContextUnaryClientInterceptor),
+				grpc.WithChainUnaryInterceptor(
+					correlation.ContextUnaryClientInterceptor,
+					tracing.UnaryClientInterceptor,
+					metricsUnaryClientInterceptor,
+				),
 			})
 		if err != nil {
 			return nil, fmt.Errorf("Failed to connect to SDK unix domain socket %s: %v", c.cfg.SdkEndpoint, err)
@@ -45,3 +57,49 @@ func (c *Client) getConn() (*grpc.ClientConn, error) {
 
 	return c.conn, nil
 }
+
+// Health returns an error if the connection to the SDK endpoint is not ready.
+// An idle connection is reconnected and waited for until ctx is done.
+func (c *Client) Health(ctx context.Context) error {
+	conn, err := c.getConn()
+	if err != nil {
+		return err
+	}
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			conn.Connect()
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection to SDK endpoint %s is %s", c.cfg.SdkEndpoint, state)
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection to SDK endpoint %s is %s: %v", c.cfg.SdkEndpoint, state, ctx.Err())
+		}
+	}
+}
+
+// metricsUnaryClientInterceptor records the latency and status code of SDK
+// requests by the backend type selected in the request metadata
+func metricsUnaryClientInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	var backendType string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(sdk.ContextDriverKey); len(values) > 0 {
+			backendType = values[0]
+		}
+	}
+	metrics.ObserveSDKRequest(path.Base(method), backendType, time.Since(start), err)
+	return err
+}
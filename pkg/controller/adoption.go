@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// adoptBucket provisions the PXBucketClaim with the existing backend bucket named
+// in spec.existingBucketName. The bucket is verified on the backend but never created.
+// The SDK cannot tell whether a bucket exists without creating it, so adoption
+// requires the admin credentials of the backend.
+func (ctrl *Controller) adoptBucket(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) error {
+	bucketID := pbc.Spec.ExistingBucketName
+	backendType := pbclass.Parameters[backendTypeKey]
+	region := pbc.Spec.Region
+	if region == "" {
+		region = pbclass.Region
+	}
+	endpoint := pbc.Spec.Endpoint
+	if endpoint == "" {
+		endpoint = pbclass.Parameters[endpointKey]
+	}
+	// Adopted data is never deleted unless the claim asks for it
+	deletionPolicy := pbc.Spec.DeletionPolicy
+	if deletionPolicy == "" {
+		deletionPolicy = crdv1alpha1.PXBucketClaimRetain
+	}
+
+	if err := ctrl.authorizeExistingBucketClaim(pbc, pbclass); err != nil {
+		errMsg := fmt.Sprintf("adoption of existing bucket denied: %v", err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AdoptBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonBucketAdoptFailed, errMsg)
+		return err
+	}
+
+	backendClient, ok := ctrl.config.Backends[backendType]
+	if !ok {
+		err := fmt.Errorf("no admin credentials configured for backend %s", backendType)
+		errMsg := fmt.Sprintf("unable to verify existing bucket %s: %v. Adopting existing buckets requires the admin credentials of the backend", bucketID, err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AdoptBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonAdminCredentialsMissing, errMsg)
+		return err
+	}
+	exists, err := backendClient.BucketExists(ctx, bucketID, region, endpoint)
+	if err == nil && !exists {
+		err = fmt.Errorf("bucket %s does not exist", bucketID)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("unable to verify existing bucket %s: %v", bucketID, err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AdoptBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonBucketAdoptFailed, errMsg)
+		return err
+	}
+
+	patched, err := ctrl.patchBucketClaimMetadata(ctx, pbc, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = addFinalizer(meta.Finalizers, bucketProvisionedFinalizer)
+		meta.Finalizers = addFinalizer(meta.Finalizers, bucketClaimProtectionFinalizer)
+	})
+	if err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AdoptBucketError", fmt.Sprintf("failed to add finalizer: %v", err))
+		return err
+	}
+	pbc = patched
+
+	bucketClaimStatusFields(pbc).setPhase(crdv1alpha1.BucketPhaseReady, reasonBucketAdopted, fmt.Sprintf("adopted existing bucket %s", bucketID))
+	pbc.Status.Provisioned = true
+	pbc.Status.BucketID = bucketID
+	pbc.Status.BucketName = bucketObjectName(bucketID)
+	pbc.Status.Region = region
+	pbc.Status.Endpoint = endpoint
+	pbc.Status.BackendType = backendType
+	pbc.Status.DeletionPolicy = deletionPolicy
+	pbc.Status.ClearBucket = parseClearBucket(pbclass.Parameters[clearBucketKey])
+	updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+	if err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AdoptBucketError", fmt.Sprintf("failed to update bucket status: %v", err))
+		return err
+	}
+	pbc = updated
+
+	if err := ctrl.createBucketObject(ctx, pbc, pbclass); err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AdoptBucketError", fmt.Sprintf("failed to create bucket object: %v", err))
+		return err
+	}
+
+	_, err = ctrl.storeBucketUpdate(pbc)
+	if err != nil {
+		return err
+	}
+
+	ctrl.eventRecorder.Event(pbc, v1.EventTypeNormal, "AdoptBucketSuccess", fmt.Sprintf("successfully adopted existing bucket %s", bucketID))
+	return nil
+}
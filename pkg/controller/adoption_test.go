@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/client/clientset/versioned/fake"
+	bucketlisters "github.com/portworx/px-object-controller/client/listers/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestAdoptBucketWithoutAdminCredentials(t *testing.T) {
+	pbc := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "tenant-a"},
+		Spec:       crdv1alpha1.BucketClaimSpec{ExistingBucketName: "legacy-bucket"},
+	}
+	pbclass := &crdv1alpha1.PXBucketClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "class"},
+		Parameters: map[string]string{
+			backendTypeKey:            "S3Driver",
+			allowedExistingBucketsKey: "legacy-bucket",
+		},
+	}
+	client := fake.NewSimpleClientset(pbc)
+	ctrl := &Controller{
+		config:          &Config{},
+		k8sBucketClient: client,
+		eventRecorder:   record.NewFakeRecorder(10),
+		bucketIndexer:   cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{bucketIDIndex: bucketIDIndexFunc}),
+		pxBucketLister:  bucketlisters.NewPXBucketLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	}
+
+	if err := ctrl.adoptBucket(context.Background(), pbc.DeepCopy(), pbclass); err == nil {
+		t.Fatalf("expected adoption without admin credentials to fail")
+	}
+
+	updated, err := client.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.Background(), pbc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get bucket claim: %v", err)
+	}
+	if updated.Status == nil || updated.Status.Provisioned {
+		t.Fatalf("expected claim not to be provisioned, got %+v", updated.Status)
+	}
+	cond := meta.FindStatusCondition(updated.Status.Conditions, crdv1alpha1.ConditionFailed)
+	if cond == nil || cond.Reason != reasonAdminCredentialsMissing {
+		t.Errorf("expected failed condition with reason %s, got %+v", reasonAdminCredentialsMissing, cond)
+	}
+}
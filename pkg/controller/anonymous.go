@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/libopenstorage/openstorage/api"
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// anonymousAccessModeKey is a PXBucketClass parameter setting the anonymous
+	// access mode of provisioned buckets. Defaults to Private.
+	anonymousAccessModeKey = commonObjectServiceKeyPrefix + "anonymous-access-mode"
+
+	// allowAnonymousAccessOverrideKey is a PXBucketClass parameter allowing
+	// PXBucketClaims to override the anonymous access mode of the class.
+	allowAnonymousAccessOverrideKey = commonObjectServiceKeyPrefix + "allow-anonymous-access-override"
+)
+
+// anonymousAccessModes maps the anonymous access modes to the SDK enum
+var anonymousAccessModes = map[crdv1alpha1.AnonymousAccessMode]api.AnonymousBucketAccessMode{
+	crdv1alpha1.AnonymousAccessPrivate:   api.AnonymousBucketAccessMode_Private,
+	crdv1alpha1.AnonymousAccessReadOnly:  api.AnonymousBucketAccessMode_ReadOnly,
+	crdv1alpha1.AnonymousAccessWriteOnly: api.AnonymousBucketAccessMode_WriteOnly,
+	crdv1alpha1.AnonymousAccessReadWrite: api.AnonymousBucketAccessMode_ReadWrite,
+}
+
+// anonymousAccessDrivers are the backend types that apply anonymous access modes
+var anonymousAccessDrivers = map[string]bool{
+	"S3Driver": true,
+}
+
+// getAnonymousAccessMode returns the anonymous access mode requested for the
+// PXBucketClaim. Adopted and bound buckets are only changed if the claim
+// requests a mode explicitly, in which case the empty mode is returned.
+func getAnonymousAccessMode(pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) (crdv1alpha1.AnonymousAccessMode, error) {
+	classMode := crdv1alpha1.AnonymousAccessMode(pbclass.Parameters[anonymousAccessModeKey])
+	if classMode == "" {
+		classMode = crdv1alpha1.AnonymousAccessPrivate
+	}
+
+	if mode := pbc.Spec.AnonymousAccessMode; mode != "" {
+		if _, ok := anonymousAccessModes[mode]; !ok {
+			return "", fmt.Errorf("invalid anonymous access mode %q", mode)
+		}
+		allowOverride, _ := strconv.ParseBool(pbclass.Parameters[allowAnonymousAccessOverrideKey])
+		if mode != classMode && !allowOverride {
+			return "", fmt.Errorf("PXBucketClass %s does not allow overriding the anonymous access mode %s", pbclass.Name, classMode)
+		}
+		return mode, nil
+	}
+
+	if pbc.Spec.BucketName != "" || pbc.Spec.ExistingBucketName != "" {
+		return "", nil
+	}
+	return classMode, nil
+}
+
+// checkAnonymousAccessSupported returns an error if the backend cannot apply the anonymous access mode
+func checkAnonymousAccessSupported(backendType string, mode crdv1alpha1.AnonymousAccessMode) error {
+	if mode == "" || mode == crdv1alpha1.AnonymousAccessPrivate || anonymousAccessDrivers[backendType] {
+		return nil
+	}
+	return fmt.Errorf("backend %s does not support anonymous access mode %s", backendType, mode)
+}
+
+// reconcileAnonymousAccess applies changes of the anonymous access mode to the
+// bucket of a provisioned PXBucketClaim.
+func (ctrl *Controller) reconcileAnonymousAccess(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) (*crdv1alpha1.PXBucketClaim, error) {
+	mode, err := getAnonymousAccessMode(pbc, pbclass)
+	if err == nil {
+		err = checkAnonymousAccessSupported(pbc.Status.BackendType, mode)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid anonymous access mode: %v", err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AnonymousAccessError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonAnonymousAccessFailed, errMsg)
+		return pbc, err
+	}
+
+	current := pbc.Status.AnonymousAccessMode
+	if mode == "" || mode == current {
+		return pbc, nil
+	}
+
+	// Buckets provisioned before the mode was recorded are private
+	dynamic := pbc.Spec.BucketName == "" && pbc.Spec.ExistingBucketName == ""
+	if !(dynamic && current == "" && mode == crdv1alpha1.AnonymousAccessPrivate) {
+		backendClient, ok := ctrl.config.Backends[pbc.Status.BackendType]
+		if !ok {
+			err = fmt.Errorf("no admin credentials configured for backend %s", pbc.Status.BackendType)
+		} else {
+			err = backendClient.SetAnonymousAccessMode(ctx, pbc.Status.BucketID, pbc.Status.Region, pbc.Status.Endpoint, anonymousAccessModes[mode])
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("failed to set anonymous access mode %s: %v", mode, err)
+			ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "AnonymousAccessError", errMsg)
+			ctrl.recordBucketClaimError(ctx, pbc, reasonAnonymousAccessFailed, errMsg)
+			return pbc, err
+		}
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeNormal, "AnonymousAccessUpdated", fmt.Sprintf("anonymous access mode of bucket %s set to %s", pbc.Status.BucketID, mode))
+	}
+
+	pbc.Status.AnonymousAccessMode = mode
+	return ctrl.updateBucketClaimStatus(ctx, pbc)
+}
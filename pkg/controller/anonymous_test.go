@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetAnonymousAccessMode(t *testing.T) {
+	newClass := func(params map[string]string) *crdv1alpha1.PXBucketClass {
+		return &crdv1alpha1.PXBucketClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "class"},
+			Parameters: params,
+		}
+	}
+	newClaim := func(spec crdv1alpha1.BucketClaimSpec) *crdv1alpha1.PXBucketClaim {
+		return &crdv1alpha1.PXBucketClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "ns"},
+			Spec:       spec,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		claim    *crdv1alpha1.PXBucketClaim
+		class    *crdv1alpha1.PXBucketClass
+		expected crdv1alpha1.AnonymousAccessMode
+		err      bool
+	}{
+		{
+			name:     "class default",
+			claim:    newClaim(crdv1alpha1.BucketClaimSpec{}),
+			class:    newClass(nil),
+			expected: crdv1alpha1.AnonymousAccessPrivate,
+		},
+		{
+			name:     "class mode",
+			claim:    newClaim(crdv1alpha1.BucketClaimSpec{}),
+			class:    newClass(map[string]string{anonymousAccessModeKey: "ReadOnly"}),
+			expected: crdv1alpha1.AnonymousAccessReadOnly,
+		},
+		{
+			name:  "override not allowed",
+			claim: newClaim(crdv1alpha1.BucketClaimSpec{AnonymousAccessMode: crdv1alpha1.AnonymousAccessReadWrite}),
+			class: newClass(nil),
+			err:   true,
+		},
+		{
+			name:     "same mode as class",
+			claim:    newClaim(crdv1alpha1.BucketClaimSpec{AnonymousAccessMode: crdv1alpha1.AnonymousAccessReadOnly}),
+			class:    newClass(map[string]string{anonymousAccessModeKey: "ReadOnly"}),
+			expected: crdv1alpha1.AnonymousAccessReadOnly,
+		},
+		{
+			name:     "override allowed",
+			claim:    newClaim(crdv1alpha1.BucketClaimSpec{AnonymousAccessMode: crdv1alpha1.AnonymousAccessWriteOnly}),
+			class:    newClass(map[string]string{allowAnonymousAccessOverrideKey: "true"}),
+			expected: crdv1alpha1.AnonymousAccessWriteOnly,
+		},
+		{
+			name:     "adopted bucket keeps its policy",
+			claim:    newClaim(crdv1alpha1.BucketClaimSpec{ExistingBucketName: "existing"}),
+			class:    newClass(map[string]string{anonymousAccessModeKey: "ReadWrite"}),
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		mode, err := getAnonymousAccessMode(tt.claim, tt.class)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		} else if mode != tt.expected {
+			t.Errorf("%s: expected mode %q, got %q", tt.name, tt.expected, mode)
+		}
+	}
+
+	if err := checkAnonymousAccessSupported("PureFBDriver", crdv1alpha1.AnonymousAccessReadOnly); err == nil {
+		t.Errorf("expected PureFBDriver to reject anonymous access")
+	}
+}
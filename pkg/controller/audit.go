@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// recreateLostBucketsKey is the PXBucketClass parameter that allows the
+	// controller to create a lost bucket again. The data is not restored.
+	recreateLostBucketsKey = commonObjectServiceKeyPrefix + "recreate-lost-buckets"
+)
+
+// auditTracker records when objects were last verified against the backend.
+// Audits are driven by the informer resync, which requeues every object once
+// per resync period.
+type auditTracker struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newAuditTracker() *auditTracker {
+	return &auditTracker{last: make(map[string]time.Time)}
+}
+
+// due returns true if the object was not verified within half the period.
+// Requeues in between, such as on updates, do not trigger another audit.
+func (t *auditTracker) due(key string, period time.Duration, now time.Time) bool {
+	if period <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, ok := t.last[key]
+	return !ok || now.Sub(last) >= period/2
+}
+
+// done records a completed audit of the object
+func (t *auditTracker) done(key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last[key] = now
+}
+
+// forget drops the object once it is gone
+func (t *auditTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.last, key)
+}
+
+// canRecreateLostBucket returns true if the class allows a lost bucket of the
+// PXBucketClaim to be created again. Adopted and bound buckets were not
+// created by the controller and are never recreated.
+func canRecreateLostBucket(pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) bool {
+	if pbc.Spec.ExistingBucketName != "" || pbc.Spec.BucketName != "" {
+		return false
+	}
+	recreate, _ := strconv.ParseBool(pbclass.Parameters[recreateLostBucketsKey])
+	return recreate
+}
+
+// auditBucketClaim verifies that the bucket of a provisioned PXBucketClaim
+// still exists on the backend. A lost bucket sets the Lost condition and is
+// created again if the class allows it. It returns true if the bucket is lost.
+func (ctrl *Controller) auditBucketClaim(ctx context.Context, key string, pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) (*crdv1alpha1.PXBucketClaim, bool, error) {
+	lost := meta.IsStatusConditionTrue(pbc.Status.Conditions, crdv1alpha1.ConditionLost)
+	now := time.Now()
+	// Lost buckets are checked on every sync so that they recover quickly
+	if !lost && !ctrl.bucketAudits.due(key, ctrl.config.ResyncPeriod, now) {
+		return pbc, false, nil
+	}
+	backendClient, ok := ctrl.config.Backends[pbc.Status.BackendType]
+	if !ok {
+		// Buckets of backends without admin credentials cannot be verified
+		return pbc, lost, nil
+	}
+
+	bucketID := pbc.Status.BucketID
+	exists, err := backendClient.BucketExists(ctx, bucketID, pbc.Status.Region, pbc.Status.Endpoint)
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("failed to verify bucket %s of bucketclaim %s: %v", bucketID, key, err)
+		return pbc, lost, nil
+	}
+	ctrl.bucketAudits.done(key, now)
+
+	s := bucketClaimStatusFields(pbc)
+	if exists {
+		if !lost {
+			return pbc, false, nil
+		}
+		msg := fmt.Sprintf("bucket %s exists on the backend again", bucketID)
+		s.setPhase(crdv1alpha1.BucketPhaseReady, reasonBucketFound, msg)
+		s.setCondition(crdv1alpha1.ConditionLost, metav1.ConditionFalse, reasonBucketFound, msg)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeNormal, "BucketFound", msg)
+		updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+		if err != nil {
+			return pbc, false, err
+		}
+		return updated, false, nil
+	}
+
+	msg := fmt.Sprintf("bucket %s no longer exists on the backend", bucketID)
+	if canRecreateLostBucket(pbc, pbclass) {
+		_, err = ctrl.bucketClient.CreateBucket(ctx, &api.BucketCreateRequest{
+			Name:                      bucketID,
+			Region:                    pbc.Status.Region,
+			Endpoint:                  pbc.Status.Endpoint,
+			AnonymousBucketAccessMode: anonymousAccessModes[pbc.Status.AnonymousAccessMode],
+		})
+		if err == nil {
+			ctrl.tagBucket(ctx, pbc.Status.BackendType, bucketID, pbc.Status.Region, pbc.Status.Endpoint)
+			msg = fmt.Sprintf("bucket %s no longer existed on the backend and has been recreated empty", bucketID)
+			s.setPhase(crdv1alpha1.BucketPhaseReady, reasonBucketRecreated, msg)
+			s.setCondition(crdv1alpha1.ConditionLost, metav1.ConditionFalse, reasonBucketRecreated, msg)
+			ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BucketRecreated", msg)
+			updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+			if err != nil {
+				return pbc, false, err
+			}
+			return updated, false, nil
+		}
+		msg = fmt.Sprintf("%s and could not be recreated: %v", msg, err)
+	}
+	if lost {
+		// Already recorded. Writing the status again would requeue the claim.
+		logrus.WithContext(ctx).Infof("bucketclaim %s: %s", key, msg)
+		return pbc, true, nil
+	}
+
+	s.setFailed(reasonBucketLost, msg)
+	s.setCondition(crdv1alpha1.ConditionLost, metav1.ConditionTrue, reasonBucketLost, msg)
+	ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BucketLost", msg)
+	updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+	if err != nil {
+		return pbc, true, err
+	}
+	return updated, true, nil
+}
+
+// auditBucketAccess verifies that the bucket and, for backends with tracked
+// keys, the access key of a granted PXBucketAccess still exist on the backend.
+// Missing ones set the Degraded condition.
+func (ctrl *Controller) auditBucketAccess(ctx context.Context, key string, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass) (*crdv1alpha1.PXBucketAccess, error) {
+	degraded := meta.FindStatusCondition(pba.Status.Conditions, crdv1alpha1.ConditionDegraded)
+	isDegraded := degraded != nil && degraded.Status == metav1.ConditionTrue
+	now := time.Now()
+	if !isDegraded && !ctrl.accessAudits.due(key, ctrl.config.ResyncPeriod, now) {
+		return pba, nil
+	}
+	backendClient, ok := ctrl.config.Backends[pba.Status.BackendType]
+	if !ok {
+		return pba, nil
+	}
+
+	var reason, msg string
+	exists, err := backendClient.BucketExists(ctx, pba.Status.BucketId, pbclass.Region, pbclass.Parameters[endpointKey])
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("failed to verify bucket %s of bucketaccess %s: %v", pba.Status.BucketId, key, err)
+		return pba, nil
+	}
+	if !exists {
+		reason = reasonBucketLost
+		msg = fmt.Sprintf("bucket %s no longer exists on the backend", pba.Status.BucketId)
+	} else if rotationDrivers[pba.Status.BackendType] && pba.Status.AccessKeyId != "" {
+		keys, err := backendClient.ListAccessKeys(ctx, pba.Status.AccountId)
+		if err != nil {
+			logrus.WithContext(ctx).Errorf("failed to verify access key of bucketaccess %s: %v", key, err)
+			return pba, nil
+		}
+		if _, ok := keys[pba.Status.AccessKeyId]; !ok {
+			reason = reasonAccessKeyLost
+			msg = fmt.Sprintf("access key %s no longer exists on the backend, annotate with %s to issue a new key", pba.Status.AccessKeyId, rotateCredentialsKey)
+		}
+	}
+	ctrl.accessAudits.done(key, now)
+
+	s := bucketAccessStatusFields(pba)
+	if reason == "" {
+		if !isDegraded {
+			return pba, nil
+		}
+		msg = fmt.Sprintf("bucket %s and access key are available", pba.Status.BucketId)
+		s.setCondition(crdv1alpha1.ConditionDegraded, metav1.ConditionFalse, reasonAccessVerified, msg)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeNormal, "AccessVerified", msg)
+	} else {
+		if isDegraded && degraded.Reason == reason {
+			// Already recorded. Writing the status again would requeue the access.
+			return pba, nil
+		}
+		s.setCondition(crdv1alpha1.ConditionDegraded, metav1.ConditionTrue, reason, msg)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, reason, msg)
+	}
+	return ctrl.updateBucketAccessStatus(ctx, pba)
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+)
+
+func TestAuditTrackerDue(t *testing.T) {
+	tracker := newAuditTracker()
+	now := time.Now()
+	period := 10 * time.Minute
+
+	if tracker.due("ns/claim", 0, now) {
+		t.Errorf("expected no audit without a resync period")
+	}
+	if !tracker.due("ns/claim", period, now) {
+		t.Errorf("expected first audit to be due")
+	}
+	tracker.done("ns/claim", now)
+	if tracker.due("ns/claim", period, now.Add(time.Minute)) {
+		t.Errorf("expected no audit on a requeue shortly after the last one")
+	}
+	// The resync may requeue the object slightly before a full period has passed
+	if !tracker.due("ns/claim", period, now.Add(period-time.Second)) {
+		t.Errorf("expected audit on the next resync")
+	}
+	tracker.forget("ns/claim")
+	if !tracker.due("ns/claim", period, now.Add(time.Minute)) {
+		t.Errorf("expected audit of a forgotten object to be due")
+	}
+}
+
+func TestCanRecreateLostBucket(t *testing.T) {
+	pbclass := &crdv1alpha1.PXBucketClass{Parameters: map[string]string{recreateLostBucketsKey: "true"}}
+	pbc := &crdv1alpha1.PXBucketClaim{}
+	if !canRecreateLostBucket(pbc, pbclass) {
+		t.Errorf("expected bucket to be recreated when the class allows it")
+	}
+
+	adopted := &crdv1alpha1.PXBucketClaim{Spec: crdv1alpha1.BucketClaimSpec{ExistingBucketName: "existing"}}
+	if canRecreateLostBucket(adopted, pbclass) {
+		t.Errorf("expected adopted bucket never to be recreated")
+	}
+
+	if canRecreateLostBucket(pbc, &crdv1alpha1.PXBucketClass{}) {
+		t.Errorf("expected bucket not to be recreated by default")
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// allowedNamespacesKey is a PXBucketClaim annotation set by the bucket owner.
+	// It holds a comma separated list of namespaces whose PXBucketAccesses may
+	// reference the claim's bucket through spec.existingBucketId.
+	allowedNamespacesKey = commonObjectServiceKeyPrefix + "allowed-namespaces"
+
+	// allowedExistingBucketsKey is a PXBucketClass parameter holding a comma
+	// separated list of buckets that are not provisioned by a PXBucketClaim
+	// and may be accessed through spec.existingBucketId or adopted through
+	// spec.existingBucketName. "*" allows any such bucket that no other
+	// PXBucketClaim claims.
+	allowedExistingBucketsKey = commonObjectServiceKeyPrefix + "allowed-existing-buckets"
+)
+
+// authorizeExistingBucketAccess checks whether the PXBucketAccess may be granted
+// access to the bucket in spec.existingBucketId.
+//
+// A bucket provisioned by a PXBucketClaim belongs to the claim's namespace.
+// Accesses from other namespaces must be allowed by the claim owner with the
+// allowed-namespaces annotation. A bucket managed by a PXBucket belongs to the
+// namespace of its claimRef. Other buckets must be allowed by the PXBucketClass
+// of the access, where "*" does not cover buckets claimed by a PXBucketClaim.
+func (ctrl *Controller) authorizeExistingBucketAccess(pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass) error {
+	bucketID := pba.Spec.ExistingBucketId
+
+	objs, err := ctrl.bucketIndexer.ByIndex(bucketIDIndex, bucketID)
+	if err != nil {
+		return err
+	}
+	if len(objs) > 0 {
+		for _, obj := range objs {
+			pbc, ok := obj.(*crdv1alpha1.PXBucketClaim)
+			if !ok {
+				continue
+			}
+			if pbc.Namespace == pba.Namespace || listContains(pbc.Annotations[allowedNamespacesKey], pba.Namespace) {
+				return nil
+			}
+		}
+		return fmt.Errorf("bucket %s belongs to a PXBucketClaim in another namespace and namespace %s is not listed in its %s annotation",
+			bucketID, pba.Namespace, allowedNamespacesKey)
+	}
+
+	pbs, err := ctrl.getPXBucketsByID(bucketID)
+	if err != nil {
+		return err
+	}
+	if len(pbs) > 0 {
+		for _, pb := range pbs {
+			if pb.Spec.ClaimRef != nil && pb.Spec.ClaimRef.Namespace == pba.Namespace {
+				return nil
+			}
+		}
+		return fmt.Errorf("bucket %s is managed by PXBucket %s whose claimRef is not in namespace %s",
+			bucketID, pbs[0].Name, pba.Namespace)
+	}
+
+	allowed := pbclass.Parameters[allowedExistingBucketsKey]
+	if listContains(allowed, bucketID) {
+		return nil
+	}
+	if strings.TrimSpace(allowed) == "*" {
+		return ctrl.checkBucketUnclaimed(bucketID, "")
+	}
+	return fmt.Errorf("bucket %s is not provisioned by a PXBucketClaim and is not listed in the %s parameter of PXBucketClass %s",
+		bucketID, allowedExistingBucketsKey, pbclass.Name)
+}
+
+// authorizeExistingBucketClaim checks whether the PXBucketClaim may adopt the
+// bucket in spec.existingBucketName. The bucket must not belong to another
+// PXBucketClaim or PXBucket and must be allowed by the PXBucketClass of the claim.
+func (ctrl *Controller) authorizeExistingBucketClaim(pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) error {
+	bucketID := pbc.Spec.ExistingBucketName
+
+	if err := ctrl.checkBucketIDUnused(pbc, bucketID); err != nil {
+		return err
+	}
+
+	allowed := pbclass.Parameters[allowedExistingBucketsKey]
+	if listContains(allowed, bucketID) {
+		return nil
+	}
+	if strings.TrimSpace(allowed) == "*" {
+		return ctrl.checkBucketUnclaimed(bucketID, pbc.UID)
+	}
+	return fmt.Errorf("bucket %s is not listed in the %s parameter of PXBucketClass %s",
+		bucketID, allowedExistingBucketsKey, pbclass.Name)
+}
+
+// getPXBucketsByID returns the PXBuckets managing the bucket
+func (ctrl *Controller) getPXBucketsByID(bucketID string) ([]*crdv1alpha1.PXBucket, error) {
+	pbs, err := ctrl.pxBucketLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var matching []*crdv1alpha1.PXBucket
+	for _, pb := range pbs {
+		if pb.Name == bucketID || pb.Spec.BucketID == bucketID {
+			matching = append(matching, pb)
+		}
+	}
+	return matching, nil
+}
+
+// checkBucketUnclaimed returns an error if a PXBucketClaim other than the one
+// with the given UID provisions, adopts or binds the bucket, even if it has not
+// finished yet. Such buckets are known to the controller and are never covered
+// by "*" in the allowed-existing-buckets parameter.
+func (ctrl *Controller) checkBucketUnclaimed(bucketID string, uid types.UID) error {
+	for _, obj := range ctrl.bucketIndexer.List() {
+		pbc, ok := obj.(*crdv1alpha1.PXBucketClaim)
+		if !ok || (uid != "" && pbc.UID == uid) {
+			continue
+		}
+		if (pbc.Status != nil && pbc.Status.BucketID == bucketID) || pbc.Spec.ExistingBucketName == bucketID || pbc.Spec.BucketName == bucketID {
+			return fmt.Errorf("bucket %s is claimed by PXBucketClaim %s/%s and is not covered by \"*\" in the %s parameter",
+				bucketID, pbc.Namespace, pbc.Name, allowedExistingBucketsKey)
+		}
+	}
+	return nil
+}
+
+// listContains returns true if value is an entry of the comma separated list
+func listContains(list, value string) bool {
+	for _, entry := range strings.Split(list, ",") {
+		if strings.TrimSpace(entry) == value {
+			return true
+		}
+	}
+	return false
+}
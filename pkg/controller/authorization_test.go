@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	bucketlisters "github.com/portworx/px-object-controller/client/listers/objectservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestAuthorizeExistingBucketAccess(t *testing.T) {
+	owner := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claim",
+			Namespace: "tenant-a",
+			Annotations: map[string]string{
+				allowedNamespacesKey: "tenant-b, tenant-c",
+			},
+		},
+		Status: &crdv1alpha1.BucketClaimStatus{
+			BucketID: "px-os-owned",
+		},
+	}
+	adopter := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "adopter",
+			Namespace: "tenant-a",
+		},
+		Spec: crdv1alpha1.BucketClaimSpec{
+			ExistingBucketName: "adopting-bucket",
+		},
+	}
+	bucketIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{bucketIDIndex: bucketIDIndexFunc})
+	for _, pbc := range []*crdv1alpha1.PXBucketClaim{owner, adopter} {
+		if err := bucketIndexer.Add(pbc); err != nil {
+			t.Fatalf("failed to add bucket claim to indexer: %v", err)
+		}
+	}
+	pxBucketIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pb := range []*crdv1alpha1.PXBucket{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "px-os-released"},
+			Spec: crdv1alpha1.BucketSpec{
+				BucketID: "px-os-released",
+				ClaimRef: &crdv1alpha1.BucketClaimReference{Namespace: "tenant-a", Name: "deleted-claim"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "static"},
+			Spec:       crdv1alpha1.BucketSpec{BucketID: "static-bucket"},
+		},
+	} {
+		if err := pxBucketIndexer.Add(pb); err != nil {
+			t.Fatalf("failed to add bucket to indexer: %v", err)
+		}
+	}
+	ctrl := &Controller{
+		bucketIndexer:  bucketIndexer,
+		pxBucketLister: bucketlisters.NewPXBucketLister(pxBucketIndexer),
+	}
+
+	testCases := []struct {
+		name       string
+		namespace  string
+		bucketID   string
+		parameters map[string]string
+		allowed    bool
+	}{
+		{
+			name:      "owner namespace",
+			namespace: "tenant-a",
+			bucketID:  "px-os-owned",
+			allowed:   true,
+		},
+		{
+			name:      "namespace granted by bucket owner",
+			namespace: "tenant-c",
+			bucketID:  "px-os-owned",
+			allowed:   true,
+		},
+		{
+			name:      "namespace not granted by bucket owner",
+			namespace: "tenant-d",
+			bucketID:  "px-os-owned",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "*",
+			},
+			allowed: false,
+		},
+		{
+			name:      "unowned bucket not in class allow-list",
+			namespace: "tenant-a",
+			bucketID:  "legacy-bucket",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "other-bucket",
+			},
+			allowed: false,
+		},
+		{
+			name:      "unowned bucket in class allow-list",
+			namespace: "tenant-a",
+			bucketID:  "legacy-bucket",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "other-bucket,legacy-bucket",
+			},
+			allowed: true,
+		},
+		{
+			name:      "unowned bucket allowed by wildcard",
+			namespace: "tenant-b",
+			bucketID:  "legacy-bucket",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "*",
+			},
+			allowed: true,
+		},
+		{
+			name:      "bucket being adopted is not covered by wildcard",
+			namespace: "tenant-b",
+			bucketID:  "adopting-bucket",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "*",
+			},
+			allowed: false,
+		},
+		{
+			name:      "PXBucket in claimRef namespace",
+			namespace: "tenant-a",
+			bucketID:  "px-os-released",
+			allowed:   true,
+		},
+		{
+			name:      "PXBucket in another claimRef namespace",
+			namespace: "tenant-b",
+			bucketID:  "px-os-released",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "*",
+			},
+			allowed: false,
+		},
+		{
+			name:      "PXBucket without claimRef",
+			namespace: "tenant-b",
+			bucketID:  "static-bucket",
+			parameters: map[string]string{
+				allowedExistingBucketsKey: "static-bucket",
+			},
+			allowed: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pba := &crdv1alpha1.PXBucketAccess{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "access",
+					Namespace: tc.namespace,
+				},
+				Spec: crdv1alpha1.BucketAccessSpec{
+					ExistingBucketId: tc.bucketID,
+				},
+			}
+			pbclass := &crdv1alpha1.PXBucketClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "class",
+				},
+				Parameters: tc.parameters,
+			}
+
+			err := ctrl.authorizeExistingBucketAccess(pba, pbclass)
+			if tc.allowed && err != nil {
+				t.Fatalf("expected access to be allowed, got %v", err)
+			}
+			if !tc.allowed && err == nil {
+				t.Fatalf("expected access to be denied")
+			}
+		})
+	}
+}
+
+func TestAuthorizeExistingBucketClaim(t *testing.T) {
+	owner := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claim",
+			Namespace: "tenant-a",
+			UID:       "owner-uid",
+		},
+		Status: &crdv1alpha1.BucketClaimStatus{
+			BucketID: "owned-bucket",
+		},
+	}
+	bucketIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{bucketIDIndex: bucketIDIndexFunc})
+	if err := bucketIndexer.Add(owner); err != nil {
+		t.Fatalf("failed to add bucket claim to indexer: %v", err)
+	}
+	pxBucketIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := pxBucketIndexer.Add(&crdv1alpha1.PXBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "static-bucket"},
+		Spec:       crdv1alpha1.BucketSpec{BucketID: "static-bucket"},
+	}); err != nil {
+		t.Fatalf("failed to add bucket to indexer: %v", err)
+	}
+	ctrl := &Controller{
+		bucketIndexer:  bucketIndexer,
+		pxBucketLister: bucketlisters.NewPXBucketLister(pxBucketIndexer),
+	}
+	pbclass := &crdv1alpha1.PXBucketClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "class"},
+		Parameters: map[string]string{
+			allowedExistingBucketsKey: "legacy-bucket, owned-bucket, static-bucket",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		uid      string
+		bucketID string
+		allowed  bool
+	}{
+		{
+			name:     "bucket in class allow-list",
+			uid:      "new-uid",
+			bucketID: "legacy-bucket",
+			allowed:  true,
+		},
+		{
+			name:     "bucket not in class allow-list",
+			uid:      "new-uid",
+			bucketID: "other-bucket",
+			allowed:  false,
+		},
+		{
+			name:     "bucket owned by another claim",
+			uid:      "new-uid",
+			bucketID: "owned-bucket",
+			allowed:  false,
+		},
+		{
+			name:     "bucket owned by the claim itself",
+			uid:      "owner-uid",
+			bucketID: "owned-bucket",
+			allowed:  true,
+		},
+		{
+			name:     "bucket managed by a PXBucket",
+			uid:      "new-uid",
+			bucketID: "static-bucket",
+			allowed:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pbc := &crdv1alpha1.PXBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "adopter",
+					Namespace: "tenant-b",
+					UID:       types.UID(tc.uid),
+				},
+				Spec: crdv1alpha1.BucketClaimSpec{
+					ExistingBucketName: tc.bucketID,
+				},
+			}
+			err := ctrl.authorizeExistingBucketClaim(pbc, pbclass)
+			if tc.allowed && err != nil {
+				t.Fatalf("expected adoption to be allowed, got %v", err)
+			}
+			if !tc.allowed && err == nil {
+				t.Fatalf("expected adoption to be denied")
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// bucketObjectName returns the name of the PXBucket recording the given bucket.
+// Bucket IDs that are not valid object names, such as ones with uppercase
+// letters or underscores, are replaced by a name derived from their hash. The
+// bucket ID itself is always kept in spec.bucketId.
+func bucketObjectName(bucketID string) string {
+	if len(validation.IsDNS1123Subdomain(bucketID)) == 0 {
+		return bucketID
+	}
+	sum := sha256.Sum256([]byte(bucketID))
+	return bucketIDPrefix + hex.EncodeToString(sum[:])[:16]
+}
+
+// newBucketClaimReference returns a reference to the given PXBucketClaim
+func newBucketClaimReference(pbc *crdv1alpha1.PXBucketClaim) *crdv1alpha1.BucketClaimReference {
+	return &crdv1alpha1.BucketClaimReference{
+		Namespace: pbc.Namespace,
+		Name:      pbc.Name,
+		UID:       pbc.UID,
+	}
+}
+
+// isBucketBoundTo returns true if the PXBucket is bound to the given PXBucketClaim
+func isBucketBoundTo(pb *crdv1alpha1.PXBucket, pbc *crdv1alpha1.PXBucketClaim) bool {
+	return pb.Spec.ClaimRef != nil && pb.Spec.ClaimRef.UID == pbc.UID
+}
+
+// getBucketPhase returns the binding phase of the PXBucket. Buckets created
+// without a status, such as statically created ones, are available.
+func getBucketPhase(pb *crdv1alpha1.PXBucket) crdv1alpha1.BucketBindingPhase {
+	if pb.Status == nil || pb.Status.Phase == "" {
+		return crdv1alpha1.BucketAvailable
+	}
+	return pb.Status.Phase
+}
+
+// checkBucketBindable returns an error if the PXBucket cannot be bound to the PXBucketClaim.
+//   - A bucket already bound to the claim is bindable again.
+//   - An available bucket is bindable by any claim, unless its claimRef
+//     reserves it for a specific claim.
+//   - A released bucket is bindable by claims in the namespace of its last claim.
+func checkBucketBindable(pb *crdv1alpha1.PXBucket, pbc *crdv1alpha1.PXBucketClaim) error {
+	if isBucketBoundTo(pb, pbc) {
+		return nil
+	}
+
+	ref := pb.Spec.ClaimRef
+	switch getBucketPhase(pb) {
+	case crdv1alpha1.BucketAvailable:
+		if ref == nil || (ref.UID == "" && ref.Namespace == pbc.Namespace && ref.Name == pbc.Name) {
+			return nil
+		}
+		return fmt.Errorf("PXBucket %s is reserved for PXBucketClaim %s/%s", pb.Name, ref.Namespace, ref.Name)
+	case crdv1alpha1.BucketReleased:
+		if ref == nil || ref.Namespace == pbc.Namespace {
+			return nil
+		}
+		return fmt.Errorf("PXBucket %s was released by a PXBucketClaim in namespace %s", pb.Name, ref.Namespace)
+	}
+
+	if ref != nil {
+		return fmt.Errorf("PXBucket %s is already bound to PXBucketClaim %s/%s", pb.Name, ref.Namespace, ref.Name)
+	}
+	return fmt.Errorf("PXBucket %s is already bound", pb.Name)
+}
+
+// setBucketPhase moves the PXBucket to the given phase and persists the status
+func (ctrl *Controller) setBucketPhase(ctx context.Context, pb *crdv1alpha1.PXBucket, phase crdv1alpha1.BucketBindingPhase) (*crdv1alpha1.PXBucket, error) {
+	if pb.Status != nil && pb.Status.Phase == phase {
+		return pb, nil
+	}
+	now := metav1.Now()
+	pb.Status = &crdv1alpha1.BucketStatus{
+		Phase:              phase,
+		LastTransitionTime: &now,
+	}
+	logrus.WithContext(ctx).Infof("bucket %s is %s", pb.Name, phase)
+	return ctrl.updateBucketStatus(ctx, pb)
+}
+
+// createBucketObject records a dynamically provisioned bucket as a PXBucket bound to its PXBucketClaim
+func (ctrl *Controller) createBucketObject(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) error {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBuckets()
+	pb, err := client.Create(ctx, &crdv1alpha1.PXBucket{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: pbc.Status.BucketName,
+		},
+		Spec: crdv1alpha1.BucketSpec{
+			BucketID:        pbc.Status.BucketID,
+			Region:          pbc.Status.Region,
+			Endpoint:        pbc.Status.Endpoint,
+			BackendType:     pbc.Status.BackendType,
+			DeletionPolicy:  pbc.Status.DeletionPolicy,
+			ClearBucket:     pbc.Status.ClearBucket,
+			BucketClassName: pbclass.Name,
+			ClaimRef:        newBucketClaimReference(pbc),
+		},
+	}, metav1.CreateOptions{})
+	if k8s_errors.IsAlreadyExists(err) {
+		pb, err = client.Get(ctx, pbc.Status.BucketName, metav1.GetOptions{})
+		if err == nil && !isBucketBoundTo(pb, pbc) {
+			err = fmt.Errorf("PXBucket %s already exists and is not bound to PXBucketClaim %s/%s", pb.Name, pbc.Namespace, pbc.Name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = ctrl.setBucketPhase(ctx, pb, crdv1alpha1.BucketBound)
+	return err
+}
+
+// ensureBucketObject makes sure a provisioned PXBucketClaim has a PXBucket.
+// Claims provisioned by older versions only record the bucket in their status.
+func (ctrl *Controller) ensureBucketObject(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) error {
+	if pbc.Status.BucketName == "" {
+		pbc.Status.BucketName = bucketObjectName(pbc.Status.BucketID)
+		updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+		if err != nil {
+			return err
+		}
+		*pbc = *updated
+	}
+
+	_, err := ctrl.pxBucketLister.Get(pbc.Status.BucketName)
+	if err == nil || !k8s_errors.IsNotFound(err) {
+		return err
+	}
+	if pbc.Spec.BucketName != "" {
+		// Bound buckets are owned by the admin and are not recreated
+		return nil
+	}
+
+	logrus.WithContext(ctx).Infof("creating missing bucket object %s for bucketclaim %s/%s", pbc.Status.BucketName, pbc.Namespace, pbc.Name)
+	return ctrl.createBucketObject(ctx, pbc, pbclass)
+}
+
+// bindBucketClaim binds the PXBucketClaim to the existing PXBucket named in its spec.
+// No backend bucket is created. The claim takes over the bucket settings from the PXBucket.
+func (ctrl *Controller) bindBucketClaim(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) error {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBuckets()
+	pb, err := client.Get(ctx, pbc.Spec.BucketName, metav1.GetOptions{})
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get bucket %s: %v", pbc.Spec.BucketName, err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BindBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonBucketBindFailed, errMsg)
+		return err
+	}
+	if err := checkBucketBindable(pb, pbc); err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BindBucketError", err.Error())
+		ctrl.recordBucketClaimError(ctx, pbc, reasonBucketBindFailed, err.Error())
+		return err
+	}
+
+	// Add the finalizers before binding so that the bucket is always
+	// released or deleted when the claim goes away.
+	patched, err := ctrl.patchBucketClaimMetadata(ctx, pbc, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = addFinalizer(meta.Finalizers, bucketProvisionedFinalizer)
+		meta.Finalizers = addFinalizer(meta.Finalizers, bucketClaimProtectionFinalizer)
+	})
+	if err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BindBucketError", fmt.Sprintf("failed to add finalizer: %v", err))
+		return err
+	}
+	pbc = patched
+
+	if !isBucketBoundTo(pb, pbc) {
+		pb.Spec.ClaimRef = newBucketClaimReference(pbc)
+		pb, err = client.Update(ctx, pb, metav1.UpdateOptions{})
+		if err != nil {
+			errMsg := fmt.Sprintf("failed to bind bucket %s: %v", pbc.Spec.BucketName, err)
+			ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BindBucketError", errMsg)
+			ctrl.recordBucketClaimError(ctx, pbc, reasonBucketBindFailed, errMsg)
+			return err
+		}
+	}
+	pb, err = ctrl.setBucketPhase(ctx, pb, crdv1alpha1.BucketBound)
+	if err != nil {
+		return err
+	}
+
+	bucketClaimStatusFields(pbc).setPhase(crdv1alpha1.BucketPhaseReady, reasonBucketBound, fmt.Sprintf("bound to bucket %s", pb.Name))
+	pbc.Status.Provisioned = true
+	pbc.Status.BucketName = pb.Name
+	pbc.Status.BucketID = pb.Spec.BucketID
+	pbc.Status.Region = pb.Spec.Region
+	pbc.Status.Endpoint = pb.Spec.Endpoint
+	pbc.Status.BackendType = pb.Spec.BackendType
+	pbc.Status.DeletionPolicy = pb.Spec.DeletionPolicy
+	pbc.Status.ClearBucket = pb.Spec.ClearBucket
+	updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+	if err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BindBucketError", fmt.Sprintf("failed to update bucket status: %v", err))
+		return err
+	}
+	pbc = updated
+
+	_, err = ctrl.storeBucketUpdate(pbc)
+	if err != nil {
+		return err
+	}
+
+	ctrl.eventRecorder.Event(pbc, v1.EventTypeNormal, "BindBucketSuccess", fmt.Sprintf("successfully bound to bucket %s", pb.Name))
+	return nil
+}
+
+// releaseBucketObject marks the PXBucket bound to the deleted PXBucketClaim as released
+func (ctrl *Controller) releaseBucketObject(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, name string) error {
+	pb, err := ctrl.k8sBucketClient.ObjectV1alpha1().PXBuckets().Get(ctx, name, metav1.GetOptions{})
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !isBucketBoundTo(pb, pbc) {
+		return nil
+	}
+
+	_, err = ctrl.setBucketPhase(ctx, pb, crdv1alpha1.BucketReleased)
+	return err
+}
+
+// deleteBucketObject removes the PXBucket of a deleted backend bucket
+func (ctrl *Controller) deleteBucketObject(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, name string) error {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBuckets()
+	pb, err := client.Get(ctx, name, metav1.GetOptions{})
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !isBucketBoundTo(pb, pbc) {
+		return nil
+	}
+
+	err = client.Delete(ctx, name, metav1.DeleteOptions{})
+	if k8s_errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestCheckBucketBindable(t *testing.T) {
+	pbc := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claim",
+			Namespace: "team-a",
+			UID:       "new-uid",
+		},
+	}
+	newBucket := func(phase crdv1alpha1.BucketBindingPhase, ref *crdv1alpha1.BucketClaimReference) *crdv1alpha1.PXBucket {
+		pb := &crdv1alpha1.PXBucket{
+			ObjectMeta: metav1.ObjectMeta{Name: "bucket"},
+			Spec:       crdv1alpha1.BucketSpec{ClaimRef: ref},
+		}
+		if phase != "" {
+			pb.Status = &crdv1alpha1.BucketStatus{Phase: phase}
+		}
+		return pb
+	}
+
+	testCases := []struct {
+		name     string
+		bucket   *crdv1alpha1.PXBucket
+		bindable bool
+	}{
+		{
+			name:     "static bucket without status",
+			bucket:   newBucket("", nil),
+			bindable: true,
+		},
+		{
+			name:     "available bucket reserved for the claim",
+			bucket:   newBucket(crdv1alpha1.BucketAvailable, &crdv1alpha1.BucketClaimReference{Namespace: "team-a", Name: "claim"}),
+			bindable: true,
+		},
+		{
+			name:     "available bucket reserved for another claim",
+			bucket:   newBucket(crdv1alpha1.BucketAvailable, &crdv1alpha1.BucketClaimReference{Namespace: "team-a", Name: "other"}),
+			bindable: false,
+		},
+		{
+			name:     "already bound to the claim",
+			bucket:   newBucket(crdv1alpha1.BucketBound, &crdv1alpha1.BucketClaimReference{Namespace: "team-a", Name: "claim", UID: "new-uid"}),
+			bindable: true,
+		},
+		{
+			name:     "bound to another claim",
+			bucket:   newBucket(crdv1alpha1.BucketBound, &crdv1alpha1.BucketClaimReference{Namespace: "team-a", Name: "claim", UID: "old-uid"}),
+			bindable: false,
+		},
+		{
+			name:     "released in the same namespace",
+			bucket:   newBucket(crdv1alpha1.BucketReleased, &crdv1alpha1.BucketClaimReference{Namespace: "team-a", Name: "old", UID: "old-uid"}),
+			bindable: true,
+		},
+		{
+			name:     "released in another namespace",
+			bucket:   newBucket(crdv1alpha1.BucketReleased, &crdv1alpha1.BucketClaimReference{Namespace: "team-b", Name: "claim", UID: "old-uid"}),
+			bindable: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkBucketBindable(tc.bucket, pbc)
+			if tc.bindable && err != nil {
+				t.Fatalf("expected bucket to be bindable, got %v", err)
+			}
+			if !tc.bindable && err == nil {
+				t.Fatalf("expected bucket not to be bindable")
+			}
+		})
+	}
+}
+
+func TestBucketObjectName(t *testing.T) {
+	if name := bucketObjectName("px-os-claim"); name != "px-os-claim" {
+		t.Errorf("expected valid bucket ID to be kept, got %s", name)
+	}
+
+	for _, bucketID := range []string{"Team_A.Backups", strings.Repeat("a", 300)} {
+		name := bucketObjectName(bucketID)
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			t.Errorf("expected valid object name for %s, got %s: %v", bucketID, name, errs)
+		}
+		if !strings.HasPrefix(name, bucketIDPrefix) {
+			t.Errorf("expected generated name to start with %s, got %s", bucketIDPrefix, name)
+		}
+		if name != bucketObjectName(bucketID) {
+			t.Errorf("expected generated name for %s to be stable", bucketID)
+		}
+	}
+	if bucketObjectName("Team_A") == bucketObjectName("Team_B") {
+		t.Errorf("expected different bucket IDs to get different names")
+	}
+}
+
+func TestEnqueueBucketObjectClaims(t *testing.T) {
+	bucketIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{bucketNameIndex: bucketNameIndexFunc})
+	for _, pbc := range []*crdv1alpha1.PXBucketClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "waiting", Namespace: "team-a"},
+			Spec:       crdv1alpha1.BucketClaimSpec{BucketName: "bucket"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "team-a"},
+			Spec:       crdv1alpha1.BucketClaimSpec{BucketName: "other-bucket"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "dynamic", Namespace: "team-b"},
+		},
+	} {
+		if err := bucketIndexer.Add(pbc); err != nil {
+			t.Fatalf("failed to add claim: %v", err)
+		}
+	}
+	ctrl := &Controller{
+		bucketIndexer: bucketIndexer,
+		bucketQueue:   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	defer ctrl.bucketQueue.ShutDown()
+
+	pb := &crdv1alpha1.PXBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "bucket"},
+		Spec: crdv1alpha1.BucketSpec{
+			ClaimRef: &crdv1alpha1.BucketClaimReference{Namespace: "team-b", Name: "bound"},
+		},
+	}
+	ctrl.enqueueBucketObjectClaims(cache.DeletedFinalStateUnknown{Key: pb.Name, Obj: pb})
+
+	var keys []string
+	for ctrl.bucketQueue.Len() > 0 {
+		key, _ := ctrl.bucketQueue.Get()
+		keys = append(keys, key.(string))
+		ctrl.bucketQueue.Done(key)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "team-a/waiting,team-b/bound" {
+		t.Errorf("expected bound and waiting claims to be enqueued, got %v", keys)
+	}
+}
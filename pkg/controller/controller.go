@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/libopenstorage/openstorage/pkg/correlation"
@@ -15,7 +16,11 @@ import (
 	bucketlisters "github.com/portworx/px-object-controller/client/listers/objectservice/v1alpha1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
+	"github.com/portworx/px-object-controller/pkg/backend"
 	"github.com/portworx/px-object-controller/pkg/client"
+	"github.com/portworx/px-object-controller/pkg/kubeconfig"
+	"github.com/portworx/px-object-controller/pkg/metrics"
+	"github.com/portworx/px-object-controller/pkg/tracing"
 	v1 "k8s.io/api/core/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,10 +43,23 @@ var (
 
 // Config represents a configuration for creating a controller server
 type Config struct {
+	// KubeConfig is the config of the Kubernetes clients. It is loaded by
+	// kubeconfig.Load with its defaults if not set.
+	KubeConfig         *rest.Config
 	SdkEndpoint        string
 	ResyncPeriod       time.Duration
 	RetryIntervalStart time.Duration
 	RetryIntervalMax   time.Duration
+	// Backends are direct clients of the bucket backends by backend type,
+	// used for operations the SDK does not provide.
+	Backends map[string]*backend.Client
+	// Namespace is the namespace of the controller
+	Namespace string
+	// GCMode selects whether orphaned buckets and accounts on the backends
+	// are reported or deleted. Garbage collection is disabled by default.
+	GCMode        string
+	GCInterval    time.Duration
+	GCGracePeriod time.Duration
 }
 
 // Controller represents a controller server
@@ -57,26 +75,67 @@ type Controller struct {
 	bucketQueue        workqueue.RateLimitingInterface
 	bucketLister       bucketlisters.PXBucketClaimLister
 	bucketListerSynced cache.InformerSynced
-	bucketStore        cache.Store
+	// bucketStore is an optional cache of processed PXBucketClaims.
+	// Deletion is driven by the live object and never depends on it.
+	bucketStore cache.Store
 
 	accessQueue        workqueue.RateLimitingInterface
 	accessLister       bucketlisters.PXBucketAccessLister
 	accessListerSynced cache.InformerSynced
-	accessStore        cache.Store
+	// accessStore is an optional cache of processed PXBucketAccesses.
+	// Deletion is driven by the live object and never depends on it.
+	accessStore cache.Store
+
+	classLister       bucketlisters.PXBucketClassLister
+	classListerSynced cache.InformerSynced
+	bucketIndexer     cache.Indexer
+	accessIndexer     cache.Indexer
+
+	pxBucketLister       bucketlisters.PXBucketLister
+	pxBucketListerSynced cache.InformerSynced
+
+	accessClassLister       bucketlisters.PXBucketAccessClassLister
+	accessClassListerSynced cache.InformerSynced
+
+	// secretInformer watches the secrets written by the controller so that
+	// deleted or modified credentials secrets are repaired
+	secretInformer     cache.SharedIndexInformer
+	secretListerSynced cache.InformerSynced
+
+	// bucketAudits and accessAudits record when provisioned claims and
+	// granted accesses were last verified against the backend
+	bucketAudits *auditTracker
+	accessAudits *auditTracker
+
+	// clusterID is the cached UID of the kube-system namespace, tagged on
+	// the buckets and accounts created by the controller
+	clusterIDMu sync.Mutex
+	clusterID   string
+
+	// runState tracks the informer sync and workers for the health checks
+	runState *runState
 }
 
 // New returns a new controller server
 func New(cfg *Config) (*Controller, error) {
 
+	if err := ValidateGCMode(cfg.GCMode); err != nil {
+		return nil, err
+	}
+
 	// Get Openstorage Bucket SDK Client
 	sdkBucketClient := client.NewClient(client.Config{
 		SdkEndpoint: cfg.SdkEndpoint,
 	})
 
 	// Get general k8s clients
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, err
+	config := cfg.KubeConfig
+	if config == nil {
+		var err error
+		config, err = kubeconfig.Load(&kubeconfig.Config{})
+		if err != nil {
+			return nil, err
+		}
 	}
 	k8sClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -93,6 +152,9 @@ func New(cfg *Config) (*Controller, error) {
 		k8sBucketClient: k8sBucketClient,
 		k8sClient:       k8sClient,
 		bucketClient:    sdkBucketClient,
+		bucketAudits:    newAuditTracker(),
+		accessAudits:    newAuditTracker(),
+		runState:        newRunState(),
 	}
 
 	// Create factory and informers
@@ -100,8 +162,13 @@ func New(cfg *Config) (*Controller, error) {
 	bucketInformer := factory.Object().V1alpha1().PXBucketClaims()
 	bucketInformer.Informer().AddEventHandlerWithResyncPeriod(
 		cache.ResourceEventHandlerFuncs{
-			AddFunc:    func(obj interface{}) { ctrl.enqueueBucketWork(obj) },
-			UpdateFunc: func(oldObj, newObj interface{}) { ctrl.enqueueBucketWork(newObj) },
+			AddFunc: func(obj interface{}) { ctrl.enqueueBucketWork(obj) },
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if needsReconcile(oldObj, newObj) {
+					ctrl.enqueueBucketWork(newObj)
+				}
+				ctrl.enqueueClaimDependents(oldObj, newObj)
+			},
 			DeleteFunc: func(obj interface{}) { ctrl.enqueueBucketWork(obj) },
 		},
 		ctrl.config.ResyncPeriod,
@@ -109,12 +176,73 @@ func New(cfg *Config) (*Controller, error) {
 	accessInformer := factory.Object().V1alpha1().PXBucketAccesses()
 	accessInformer.Informer().AddEventHandlerWithResyncPeriod(
 		cache.ResourceEventHandlerFuncs{
-			AddFunc:    func(obj interface{}) { ctrl.enqueueAccessWork(obj) },
-			UpdateFunc: func(oldObj, newObj interface{}) { ctrl.enqueueAccessWork(newObj) },
-			DeleteFunc: func(obj interface{}) { ctrl.enqueueAccessWork(obj) },
+			AddFunc: func(obj interface{}) { ctrl.enqueueAccessWork(obj) },
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if needsReconcile(oldObj, newObj) {
+					ctrl.enqueueAccessWork(newObj)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				ctrl.enqueueAccessWork(obj)
+				ctrl.enqueueAccessBucketClaim(obj)
+			},
 		},
 		ctrl.config.ResyncPeriod,
 	)
+	classInformer := factory.Object().V1alpha1().PXBucketClasses()
+	classInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { ctrl.enqueueClassDependents(obj) },
+			UpdateFunc: func(oldObj, newObj interface{}) { ctrl.enqueueClassDependents(newObj) },
+		},
+	)
+
+	pxBucketInformer := factory.Object().V1alpha1().PXBuckets()
+	pxBucketInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) { ctrl.enqueueBucketObjectClaims(obj) },
+			// Phase changes are status updates, e.g. a released bucket becoming bindable
+			UpdateFunc: func(oldObj, newObj interface{}) { ctrl.enqueueBucketObjectClaims(newObj) },
+			DeleteFunc: func(obj interface{}) { ctrl.enqueueBucketObjectClaims(obj) },
+		},
+	)
+	accessClassInformer := factory.Object().V1alpha1().PXBucketAccessClasses()
+	accessClassInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) { ctrl.enqueueAccessClassDependents(newObj) },
+		},
+	)
+
+	secretInformer := cache.NewSharedIndexInformer(
+		cache.NewFilteredListWatchFromClient(k8sClient.CoreV1().RESTClient(), "secrets", v1.NamespaceAll, func(options *metav1.ListOptions) {
+			options.LabelSelector = fmt.Sprintf("%s=%s", managedByLabel, managedByValue)
+		}),
+		&v1.Secret{},
+		cfg.ResyncPeriod,
+		cache.Indexers{},
+	)
+	secretInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) { ctrl.enqueueSecretOwner(newObj) },
+			DeleteFunc: func(obj interface{}) { ctrl.enqueueSecretOwner(obj) },
+		},
+	)
+
+	// Index claims and accesses for lookups of dependent objects
+	if err := bucketInformer.Informer().AddIndexers(cache.Indexers{
+		bucketClassIndex: bucketClassIndexFunc,
+		bucketIDIndex:    bucketIDIndexFunc,
+		bucketNameIndex:  bucketNameIndexFunc,
+	}); err != nil {
+		return nil, err
+	}
+	if err := accessInformer.Informer().AddIndexers(cache.Indexers{
+		bucketClassIndex:       bucketClassIndexFunc,
+		bucketClaimIndex:       bucketClaimIndexFunc,
+		bucketAccessClassIndex: bucketAccessClassIndexFunc,
+	}); err != nil {
+		return nil, err
+	}
 
 	// Assign bucket CR listers and informers
 	bucketRateLimiter := workqueue.NewItemExponentialFailureRateLimiter(ctrl.config.RetryIntervalStart, ctrl.config.RetryIntervalMax)
@@ -122,6 +250,7 @@ func New(cfg *Config) (*Controller, error) {
 	ctrl.bucketStore = cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)
 	ctrl.bucketLister = bucketInformer.Lister()
 	ctrl.bucketListerSynced = bucketInformer.Informer().HasSynced
+	ctrl.bucketIndexer = bucketInformer.Informer().GetIndexer()
 	ctrl.bucketQueue = workqueue.NewNamedRateLimitingQueue(bucketRateLimiter, "px-object-controller-bucket")
 
 	// Assign access CR listers and informers
@@ -129,8 +258,25 @@ func New(cfg *Config) (*Controller, error) {
 	ctrl.accessStore = cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)
 	ctrl.accessLister = accessInformer.Lister()
 	ctrl.accessListerSynced = accessInformer.Informer().HasSynced
+	ctrl.accessIndexer = accessInformer.Informer().GetIndexer()
+
+	// Assign class CR listers and informers
+	ctrl.classLister = classInformer.Lister()
+	ctrl.classListerSynced = classInformer.Informer().HasSynced
+
+	// Assign bucket object listers and informers
+	ctrl.pxBucketLister = pxBucketInformer.Lister()
+	ctrl.pxBucketListerSynced = pxBucketInformer.Informer().HasSynced
+
+	// Assign access class CR listers and informers
+	ctrl.accessClassLister = accessClassInformer.Lister()
+	ctrl.accessClassListerSynced = accessClassInformer.Informer().HasSynced
 	ctrl.accessQueue = workqueue.NewNamedRateLimitingQueue(accessRateLimiter, "px-object-controller-access")
 
+	// Assign credentials secret informer
+	ctrl.secretInformer = secretInformer
+	ctrl.secretListerSynced = secretInformer.HasSynced
+
 	// Broadcaster setup
 	broadcaster := record.NewBroadcaster()
 	broadcaster.StartLogging(logrus.Infof)
@@ -144,18 +290,48 @@ func New(cfg *Config) (*Controller, error) {
 // Run starts the Px Object Service controller
 func (ctrl *Controller) Run(workers int, stopCh chan struct{}) {
 	ctrl.objectFactory.Start(stopCh)
-
-	informers := []cache.InformerSynced{ctrl.accessListerSynced, ctrl.bucketListerSynced}
-	if !cache.WaitForCacheSync(stopCh, informers...) {
-		logrus.Errorf("Cannot sync caches")
+	go ctrl.secretInformer.Run(stopCh)
+
+	ctrl.runState.start(2 * workers)
+	defer ctrl.runState.stop()
+
+	// Give up on caches that do not sync, such as on missing permissions,
+	// so that the liveness check fails and the pod is restarted
+	syncCh := make(chan struct{})
+	syncTimer := time.AfterFunc(cacheSyncTimeout, func() { close(syncCh) })
+	go func() {
+		<-stopCh
+		if syncTimer.Stop() {
+			close(syncCh)
+		}
+	}()
+	informers := []cache.InformerSynced{ctrl.accessListerSynced, ctrl.bucketListerSynced, ctrl.classListerSynced, ctrl.pxBucketListerSynced, ctrl.accessClassListerSynced, ctrl.secretListerSynced}
+	synced := cache.WaitForCacheSync(syncCh, informers...)
+	syncTimer.Stop()
+	if !synced {
+		select {
+		case <-stopCh:
+			logrus.Infof("Stopped before caches were synced")
+		default:
+			err := fmt.Errorf("caches did not sync within %v", cacheSyncTimeout)
+			logrus.Errorf("Cannot sync caches: %v", err)
+			ctrl.runState.setSynced(err)
+		}
 		return
 	}
+	ctrl.runState.setSynced(nil)
 
 	ctrl.loadCaches(ctrl.bucketLister, ctrl.accessLister)
+	if err := metrics.RegisterObjectCounter(ctrl.countObjects); err != nil {
+		logrus.Errorf("failed to register bucket metrics: %v", err)
+	}
 
 	for i := 0; i < workers; i++ {
-		go wait.Until(ctrl.bucketWorker, 0, stopCh)
-		go wait.Until(ctrl.accessWorker, 0, stopCh)
+		go ctrl.runState.runWorker(func() { wait.Until(ctrl.bucketWorker, 0, stopCh) })
+		go ctrl.runState.runWorker(func() { wait.Until(ctrl.accessWorker, 0, stopCh) })
+	}
+	if gcEnabled(ctrl.config.GCMode) && ctrl.config.GCInterval > 0 {
+		go wait.Until(ctrl.collectGarbage, ctrl.config.GCInterval, stopCh)
 	}
 
 	<-stopCh
@@ -168,13 +344,17 @@ func (ctrl *Controller) bucketWorker() {
 		return
 	}
 	defer ctrl.bucketQueue.Done(keyObj)
+	defer ctrl.runState.beginItem("bucketclaim " + keyObj.(string))()
 	ctx := correlation.WithCorrelationContext(context.Background(), "px-object-controller/pkg/controller")
+	ctx, span := tracing.Start(ctx, "processBucket", tracing.String(attributeBucketClaim, keyObj.(string)))
 
-	if err := ctrl.processBucket(ctx, keyObj.(string)); err != nil {
+	err := ctrl.processBucket(ctx, keyObj.(string))
+	span.End(err)
+	if err != nil {
 		// Rather than wait for a full resync, re-add the key to the
 		// queue to be processed.
 		ctrl.bucketQueue.AddRateLimited(keyObj)
-		logrus.WithContext(ctx).Infof("Failed to sync bucket %q, will retry again: %v", keyObj.(string), err)
+		logrus.WithContext(ctx).Infof("Failed to sync bucket %q (%d retries), will retry again: %v", keyObj.(string), ctrl.bucketQueue.NumRequeues(keyObj), err)
 	} else {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
@@ -193,58 +373,126 @@ func (ctrl *Controller) processBucket(ctx context.Context, key string) error {
 	}
 	bucketClaim, err := ctrl.bucketLister.PXBucketClaims(namespace).Get(name)
 	if err == nil && bucketClaim.ObjectMeta.DeletionTimestamp == nil {
+		bucketClaim = bucketClaim.DeepCopy()
+		var defaultErr error
+		if bucketClaim.Spec.BucketClassName == "" {
+			var defaulted *crdv1alpha1.PXBucketClaim
+			defaulted, defaultErr = ctrl.setDefaultBucketClaimClass(ctx, bucketClaim)
+			if defaultErr == nil {
+				bucketClaim = defaulted
+			}
+		}
 		var bucketClass *crdv1alpha1.PXBucketClass
 		if bucketClaim.Spec.BucketClassName != "" {
-			bucketClass, err = ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClasses().Get(ctx, bucketClaim.Spec.BucketClassName, metav1.GetOptions{})
+			bucketClass, err = ctrl.classLister.Get(bucketClaim.Spec.BucketClassName)
 			if err != nil {
-				ctrl.eventRecorder.Event(bucketClaim, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to get bucket class %v", key))
+				errMsg := fmt.Sprintf("failed to get bucket class %v", key)
+				ctrl.eventRecorder.Event(bucketClaim, v1.EventTypeWarning, "CreateBucketError", errMsg)
+				ctrl.recordBucketClaimError(ctx, bucketClaim, reasonBucketClassMissing, fmt.Sprintf("%s: %v", errMsg, err))
 				return err
 			}
 		} else {
-			errMsg := fmt.Sprintf("PXBucketClaim %v must reference a PXBucketClass", key)
+			errMsg := fmt.Sprintf("PXBucketClaim %v must reference a PXBucketClass: %v", key, defaultErr)
 			ctrl.eventRecorder.Event(bucketClaim, v1.EventTypeWarning, "CreateBucketError", errMsg)
+			ctrl.recordBucketClaimError(ctx, bucketClaim, reasonBucketClassMissing, errMsg)
 			return errors.New(errMsg)
 		}
+		tracing.SpanFromContext(ctx).SetAttributes(tracing.String(attributeBucketClass, bucketClass.Name))
 		ctx, err := ctrl.setupContextFromClass(ctx, bucketClass)
 		if err != nil {
 			ctrl.eventRecorder.Event(bucketClaim, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("invalid bucketclass: %v", err))
+			ctrl.recordBucketClaimError(ctx, bucketClaim, reasonInvalidBucketClass, fmt.Sprintf("invalid bucketclass: %v", err))
 			return err
 		}
 
 		if bucketClaim.Status != nil && bucketClaim.Status.Provisioned {
 			logrus.WithContext(ctx).Infof("bucketclaim %q already provisioned", key)
-			_, err := ctrl.storeBucketUpdate(bucketClaim)
+			// Claims provisioned by older versions do not have the protection finalizer yet
+			bucketClaim, err = ctrl.patchBucketClaimMetadata(ctx, bucketClaim, func(meta *metav1.ObjectMeta) {
+				meta.Finalizers = addFinalizer(meta.Finalizers, bucketClaimProtectionFinalizer)
+			})
+			if err != nil {
+				return err
+			}
+			if err := ctrl.ensureBucketObject(ctx, bucketClaim, bucketClass); err != nil {
+				ctrl.eventRecorder.Event(bucketClaim, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to create bucket object: %v", err))
+				return err
+			}
+			var lost bool
+			bucketClaim, lost, err = ctrl.auditBucketClaim(ctx, key, bucketClaim, bucketClass)
+			if err != nil {
+				return err
+			}
+			if lost {
+				// Checked again on the next resync
+				_, err = ctrl.storeBucketUpdate(bucketClaim)
+				return err
+			}
+			bucketClaim, err = ctrl.reconcileAnonymousAccess(ctx, bucketClaim, bucketClass)
+			if err != nil {
+				return err
+			}
+			// Recover from errors recorded after the bucket was provisioned
+			if bucketClaim.Status.Phase != crdv1alpha1.BucketPhaseReady {
+				bucketClaim, err = ctrl.setBucketClaimPhase(ctx, bucketClaim, crdv1alpha1.BucketPhaseReady, reasonBucketProvisioned, fmt.Sprintf("bucket %s is provisioned", bucketClaim.Status.BucketID))
+				if err != nil {
+					return err
+				}
+			}
+			_, err = ctrl.storeBucketUpdate(bucketClaim)
 			return err
 		}
 
+		if bucketClaim.Spec.ExistingBucketName != "" {
+			logrus.WithContext(ctx).Infof("Adopting bucket %s for bucketclaim %q", bucketClaim.Spec.ExistingBucketName, key)
+			return observeOperation(ctx, metrics.OperationCreate, func(ctx context.Context) error { return ctrl.adoptBucket(ctx, bucketClaim, bucketClass) })
+		}
+
+		if bucketClaim.Spec.BucketName != "" {
+			logrus.WithContext(ctx).Infof("Binding bucketclaim %q to bucket %s", key, bucketClaim.Spec.BucketName)
+			return observeOperation(ctx, metrics.OperationCreate, func(ctx context.Context) error { return ctrl.bindBucketClaim(ctx, bucketClaim) })
+		}
+
 		logrus.WithContext(ctx).Infof("Creating bucketclaim %q", key)
-		return ctrl.createBucket(ctx, bucketClaim, bucketClass)
+		return observeOperation(ctx, metrics.OperationCreate, func(ctx context.Context) error { return ctrl.createBucket(ctx, bucketClaim, bucketClass) })
 	}
 	if err != nil && !k8s_errors.IsNotFound(err) {
 		logrus.WithContext(ctx).Infof("error getting bucketclaim %q from informer: %v", key, err)
 		return err
 	}
-	// The bucketclaim is not in informer cache, the event must have been "delete"
-	bcObj, found, err := ctrl.bucketStore.GetByKey(key)
-	if err != nil {
-		logrus.WithContext(ctx).Infof("error getting bucketclaim %q from cache: %v", key, err)
+	if k8s_errors.IsNotFound(err) {
+		// The bucketclaim is gone. The finalizer guarantees the backend
+		// delete already completed before the object was removed.
+		logrus.WithContext(ctx).Infof("bucketclaim %q no longer exists", key)
+		ctrl.storeBucketDelete(cache.DeletedFinalStateUnknown{Key: key})
+		ctrl.bucketAudits.forget(key)
 		return nil
 	}
-	if !found {
-		// The controller has already processed the delete event and
-		// deleted the bucketclaim from its cache
+
+	// The bucketclaim is being deleted
+	bucketClaim = bucketClaim.DeepCopy()
+	if !hasFinalizer(bucketClaim.Finalizers, bucketProvisionedFinalizer) && !hasFinalizer(bucketClaim.Finalizers, bucketClaimProtectionFinalizer) {
 		logrus.WithContext(ctx).Infof("deletion of bucketclaim %q was already processed", key)
+		ctrl.storeBucketDelete(bucketClaim)
 		return nil
 	}
-	bucketclaim, ok := bcObj.(*crdv1alpha1.PXBucketClaim)
-	if !ok {
-		logrus.WithContext(ctx).Errorf("expected bc, got %+v", bcObj)
+	inUse, err := ctrl.checkBucketClaimInUse(ctx, bucketClaim)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		// The claim is requeued when the last bucketaccess is removed
+		logrus.WithContext(ctx).Infof("bucketclaim %q is still in use by bucketaccesses", key)
 		return nil
 	}
-	ctx = ctrl.setupContextFromValue(ctx, bucketclaim.Status.BackendType)
+	var backendType string
+	if bucketClaim.Status != nil {
+		backendType = bucketClaim.Status.BackendType
+	}
+	ctx = ctrl.setupContextFromValue(ctx, backendType)
 
-	logrus.WithContext(ctx).Infof("deleting bucketclaim %q with driver %s", key, bucketclaim.Status.BackendType)
-	return ctrl.deleteBucket(ctx, bucketclaim)
+	logrus.WithContext(ctx).Infof("deleting bucketclaim %q with driver %s", key, backendType)
+	return observeOperation(ctx, metrics.OperationDelete, func(ctx context.Context) error { return ctrl.deleteBucket(ctx, bucketClaim) })
 }
 
 // enqueueBucketClaimWork adds bucketclaim to given work queue.
@@ -271,13 +519,17 @@ func (ctrl *Controller) accessWorker() {
 		return
 	}
 	defer ctrl.accessQueue.Done(keyObj)
+	defer ctrl.runState.beginItem("bucketaccess " + keyObj.(string))()
 	ctx := correlation.WithCorrelationContext(context.Background(), "px-object-controller/pkg/controller")
+	ctx, span := tracing.Start(ctx, "processAccess", tracing.String(attributeBucketAccess, keyObj.(string)))
 
-	if err := ctrl.processAccess(ctx, keyObj.(string)); err != nil {
+	err := ctrl.processAccess(ctx, keyObj.(string))
+	span.End(err)
+	if err != nil {
 		// Rather than wait for a full resync, re-add the key to the
 		// queue to be processed.
 		ctrl.accessQueue.AddRateLimited(keyObj)
-		logrus.WithContext(ctx).Infof("Failed to sync bucket access %q, will retry again: %v", keyObj.(string), err)
+		logrus.WithContext(ctx).Infof("Failed to sync bucket access %q (%d retries), will retry again: %v", keyObj.(string), ctrl.accessQueue.NumRequeues(keyObj), err)
 	} else {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
@@ -296,22 +548,65 @@ func (ctrl *Controller) processAccess(ctx context.Context, key string) error {
 	}
 	bucketAccess, err := ctrl.accessLister.PXBucketAccesses(namespace).Get(name)
 	if err == nil && bucketAccess.ObjectMeta.DeletionTimestamp == nil {
+		bucketAccess = bucketAccess.DeepCopy()
+		var defaultErr error
+		if bucketAccess.Spec.BucketClassName == "" {
+			var defaulted *crdv1alpha1.PXBucketAccess
+			defaulted, defaultErr = ctrl.setDefaultBucketAccessClass(ctx, bucketAccess)
+			if defaultErr == nil {
+				bucketAccess = defaulted
+			}
+		}
 		var bucketClass *crdv1alpha1.PXBucketClass
 		if bucketAccess.Spec.BucketClassName != "" {
-			bucketClass, err = ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClasses().Get(ctx, bucketAccess.Spec.BucketClassName, metav1.GetOptions{})
+			bucketClass, err = ctrl.classLister.Get(bucketAccess.Spec.BucketClassName)
 			if err != nil {
+				ctrl.recordBucketAccessError(ctx, bucketAccess, reasonBucketClassMissing, fmt.Sprintf("failed to get bucket class %s: %v", bucketAccess.Spec.BucketClassName, err))
 				return err
 			}
 		} else {
-			return errors.New("PXBucketAccess must reference a PXBucketClass")
+			errMsg := fmt.Sprintf("PXBucketAccess must reference a PXBucketClass: %v", defaultErr)
+			ctrl.recordBucketAccessError(ctx, bucketAccess, reasonBucketClassMissing, errMsg)
+			return errors.New(errMsg)
+		}
+		tracing.SpanFromContext(ctx).SetAttributes(tracing.String(attributeBucketClass, bucketClass.Name))
+		if bucketAccess.Spec.BucketClaimName != "" {
+			tracing.SpanFromContext(ctx).SetAttributes(tracing.String(attributeBucketClaim, bucketAccess.Namespace+"/"+bucketAccess.Spec.BucketClaimName))
 		}
 		ctx, err := ctrl.setupContextFromClass(ctx, bucketClass)
 		if err != nil {
+			ctrl.recordBucketAccessError(ctx, bucketAccess, reasonInvalidBucketClass, fmt.Sprintf("invalid bucketclass: %v", err))
 			return err
 		}
 
+		var accessClass *crdv1alpha1.PXBucketAccessClass
+		if bucketAccess.Spec.BucketAccessClassName != "" {
+			accessClass, err = ctrl.accessClassLister.Get(bucketAccess.Spec.BucketAccessClassName)
+			if err != nil {
+				ctrl.recordBucketAccessError(ctx, bucketAccess, reasonBucketAccessClassMissing, fmt.Sprintf("failed to get bucket access class %s: %v", bucketAccess.Spec.BucketAccessClassName, err))
+				return err
+			}
+		}
+
 		if bucketAccess.Status != nil && bucketAccess.Status.AccessGranted {
 			logrus.WithContext(ctx).Infof("access already granted to bucket %s for bucket access %s", bucketAccess.Status.BucketId, key)
+			bucketAccess, err = ctrl.ensureSourceCredentials(ctx, bucketAccess, bucketClass, accessClass)
+			if err != nil {
+				return err
+			}
+			bucketAccess, err = ctrl.reconcileCredentials(ctx, bucketAccess, bucketClass)
+			if err != nil {
+				return err
+			}
+			bucketAccess, err = ctrl.reconcileCredentialsSecret(ctx, bucketAccess, bucketClass, accessClass)
+			if err != nil {
+				return err
+			}
+			bucketAccess, err = ctrl.auditBucketAccess(ctx, key, bucketAccess, bucketClass)
+			if err != nil {
+				return err
+			}
+			ctrl.scheduleCredentialsRotation(key, bucketAccess)
 			_, err = ctrl.storeAccessUpdate(bucketAccess)
 			return err
 		}
@@ -322,48 +617,80 @@ func (ctrl *Controller) processAccess(ctx context.Context, key string) error {
 			if err != nil {
 				errMsg := fmt.Sprintf("failed to get bucketclaim %s", bucketAccess.Spec.BucketClaimName)
 				ctrl.eventRecorder.Event(bucketAccess, v1.EventTypeWarning, "GrantAccessError", errMsg)
+				ctrl.recordBucketAccessError(ctx, bucketAccess, reasonBucketClaimMissing, fmt.Sprintf("%s: %v", errMsg, err))
 				return err
 			}
-			if pbc.Status == nil {
-				errMsg := fmt.Sprintf("bucket claim %s exists but is not yet provisioned", bucketAccess.Spec.BucketClaimName)
+			if pbc.DeletionTimestamp != nil {
+				// The claim waits for this access to be removed before its
+				// bucket is deleted, so access must not be granted anymore
+				errMsg := fmt.Sprintf("bucket claim %s is being deleted", bucketAccess.Spec.BucketClaimName)
 				ctrl.eventRecorder.Event(bucketAccess, v1.EventTypeWarning, "GrantAccessError", errMsg)
-				return fmt.Errorf(errMsg)
+				ctrl.recordBucketAccessError(ctx, bucketAccess, reasonBucketClaimDeleting, errMsg)
+				return nil
+			}
+			if !isBucketProvisioned(pbc) {
+				// The access is requeued as soon as the claim is provisioned
+				logrus.WithContext(ctx).Infof("bucket claim %s exists but is not yet provisioned", bucketAccess.Spec.BucketClaimName)
+				ctrl.setBucketAccessWaiting(ctx, bucketAccess, fmt.Sprintf("waiting for bucket claim %s to be provisioned", bucketAccess.Spec.BucketClaimName))
+				return nil
 			}
 
-			bucketID = getBucketID(pbc)
+			bucketID = pbc.Status.BucketID
 		}
 		if bucketAccess.Spec.ExistingBucketId != "" {
+			if err := ctrl.authorizeExistingBucketAccess(bucketAccess, bucketClass); err != nil {
+				errMsg := fmt.Sprintf("access to existing bucket denied: %v", err)
+				ctrl.eventRecorder.Event(bucketAccess, v1.EventTypeWarning, "GrantAccessError", errMsg)
+				ctrl.recordBucketAccessError(ctx, bucketAccess, reasonBucketAccessDenied, errMsg)
+				return err
+			}
 			bucketID = bucketAccess.Spec.ExistingBucketId
 		}
 
+		accessPolicy, err := renderAccessPolicy(bucketAccess, accessClass, bucketID)
+		if err == nil {
+			err = checkAccessPolicySupported(bucketClass.Parameters[backendTypeKey], accessPolicy)
+		}
+		if err != nil {
+			errMsg := fmt.Sprintf("invalid access policy: %v", err)
+			ctrl.eventRecorder.Event(bucketAccess, v1.EventTypeWarning, "GrantAccessError", errMsg)
+			ctrl.recordBucketAccessError(ctx, bucketAccess, reasonInvalidAccessPolicy, errMsg)
+			return err
+		}
+
 		logrus.WithContext(ctx).Infof("Creating bucketaccess %q for bucket ID %v", key, bucketID)
-		return ctrl.createAccess(ctx, bucketAccess, bucketClass, bucketID)
+		return observeOperation(ctx, metrics.OperationGrant, func(ctx context.Context) error {
+			return ctrl.createAccess(ctx, bucketAccess, bucketClass, accessClass, bucketID, accessPolicy)
+		})
 	}
 	if err != nil && !k8s_errors.IsNotFound(err) {
 		logrus.WithContext(ctx).Infof("error getting bucketaccess %q from informer: %v", key, err)
 		return err
 	}
-	// The bucketaccess is not in informer cache, the event must have been "delete"
-	bacObj, found, err := ctrl.accessStore.GetByKey(key)
-	if err != nil {
-		logrus.WithContext(ctx).Infof("error getting bucketaccess %q from cache: %v", key, err)
+	if k8s_errors.IsNotFound(err) {
+		// The bucketaccess is gone. The finalizer guarantees the backend
+		// revoke already completed before the object was removed.
+		logrus.WithContext(ctx).Infof("bucketaccess %q no longer exists", key)
+		ctrl.storeAccessDelete(cache.DeletedFinalStateUnknown{Key: key})
+		ctrl.accessAudits.forget(key)
 		return nil
 	}
-	if !found {
-		// The controller has already processed the delete event and
-		// deleted the bucketaccess from its cache
+
+	// The bucketaccess is being deleted
+	bucketAccess = bucketAccess.DeepCopy()
+	if !hasFinalizer(bucketAccess.Finalizers, accessGrantedFinalizer) {
 		logrus.WithContext(ctx).Infof("deletion of bucketaccess %q was already processed", key)
+		ctrl.storeAccessDelete(bucketAccess)
 		return nil
 	}
-	bucketaccess, ok := bacObj.(*crdv1alpha1.PXBucketAccess)
-	if !ok {
-		logrus.WithContext(ctx).Errorf("expected bc, got %+v", bacObj)
-		return nil
+	var backendType string
+	if bucketAccess.Status != nil {
+		backendType = bucketAccess.Status.BackendType
 	}
-	ctx = ctrl.setupContextFromValue(ctx, bucketaccess.Status.BackendType)
+	ctx = ctrl.setupContextFromValue(ctx, backendType)
 
 	logrus.WithContext(ctx).Infof("deleting bucketaccess %q", key)
-	return ctrl.revokeAccess(ctx, bucketaccess)
+	return observeOperation(ctx, metrics.OperationRevoke, func(ctx context.Context) error { return ctrl.revokeAccess(ctx, bucketAccess) })
 }
 
 // enqueueBucketClaimWork adds bucketclaim to given work queue.
@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+// credentialsParams are the values rendered into a credentials secret
+type credentialsParams struct {
+	// AccessKeyID is the ID of the access key
+	AccessKeyID string
+	// SecretAccessKey is the secret of the access key
+	SecretAccessKey string
+	// Endpoint is the S3 endpoint of the bucket
+	Endpoint string
+	// Region is the region of the bucket
+	Region string
+	// BucketID is the ID of the bucket
+	BucketID string
+	// Namespace is the namespace of the PXBucketAccess
+	Namespace string
+	// Name is the name of the PXBucketAccess
+	Name string
+}
+
+// credentialsRenderers render the built-in credentials formats
+var credentialsRenderers = map[crdv1alpha1.CredentialsFormat]func(*credentialsParams) (map[string]string, error){
+	crdv1alpha1.CredentialsFormatDefault:   renderDefaultCredentials,
+	crdv1alpha1.CredentialsFormatAWSEnv:    renderAWSEnvCredentials,
+	crdv1alpha1.CredentialsFormatAWSConfig: renderAWSConfigCredentials,
+	crdv1alpha1.CredentialsFormatJSON:      renderJSONCredentials,
+	crdv1alpha1.CredentialsFormatRclone:    renderRcloneCredentials,
+	crdv1alpha1.CredentialsFormatS3cmd:     renderS3cmdCredentials,
+}
+
+// getCredentialsSourceName returns the name of the secret holding the raw
+// access key of the PXBucketAccess. The credentials secret only holds the
+// rendered format, which cannot always be parsed back.
+func getCredentialsSourceName(pba *crdv1alpha1.PXBucketAccess) string {
+	return fmt.Sprintf("px-os-credentials-source-%s", pba.Name)
+}
+
+// getCredentialsFormat returns the credentials format of the PXBucketAccess
+func getCredentialsFormat(pba *crdv1alpha1.PXBucketAccess, pbaclass *crdv1alpha1.PXBucketAccessClass) crdv1alpha1.CredentialsFormat {
+	if pba.Spec.CredentialsFormat != "" {
+		return pba.Spec.CredentialsFormat
+	}
+	if pbaclass != nil && pbaclass.CredentialsFormat != "" {
+		return pbaclass.CredentialsFormat
+	}
+	return crdv1alpha1.CredentialsFormatDefault
+}
+
+// renderCredentials renders the credentials secret data in the given format
+func renderCredentials(format crdv1alpha1.CredentialsFormat, pbaclass *crdv1alpha1.PXBucketAccessClass, params *credentialsParams) (map[string]string, error) {
+	if format == crdv1alpha1.CredentialsFormatTemplate {
+		if pbaclass == nil || len(pbaclass.CredentialsTemplate) == 0 {
+			return nil, fmt.Errorf("credentials format %s requires a PXBucketAccessClass with a credentialsTemplate", format)
+		}
+		return renderCredentialsTemplate(pbaclass.CredentialsTemplate, params)
+	}
+
+	renderer, ok := credentialsRenderers[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid credentials format %q", format)
+	}
+	return renderer(params)
+}
+
+func renderDefaultCredentials(params *credentialsParams) (map[string]string, error) {
+	return map[string]string{
+		accessKeyIDKey:     params.AccessKeyID,
+		secretAccessKeyKey: params.SecretAccessKey,
+		"endpoint":         params.Endpoint,
+		"region":           params.Region,
+		"bucket-id":        params.BucketID,
+	}, nil
+}
+
+func renderAWSEnvCredentials(params *credentialsParams) (map[string]string, error) {
+	data := map[string]string{
+		"AWS_ACCESS_KEY_ID":     params.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": params.SecretAccessKey,
+		"BUCKET_NAME":           params.BucketID,
+	}
+	if params.Region != "" {
+		data["AWS_REGION"] = params.Region
+		data["AWS_DEFAULT_REGION"] = params.Region
+	}
+	if params.Endpoint != "" {
+		data["AWS_ENDPOINT_URL"] = params.Endpoint
+	}
+	return data, nil
+}
+
+func renderAWSConfigCredentials(params *credentialsParams) (map[string]string, error) {
+	credentials := fmt.Sprintf("[default]\naws_access_key_id = %s\naws_secret_access_key = %s\n", params.AccessKeyID, params.SecretAccessKey)
+	config := "[default]\n"
+	if params.Region != "" {
+		config += fmt.Sprintf("region = %s\n", params.Region)
+	}
+	if params.Endpoint != "" {
+		config += fmt.Sprintf("endpoint_url = %s\n", params.Endpoint)
+	}
+	return map[string]string{
+		"credentials": credentials,
+		"config":      config,
+	}, nil
+}
+
+func renderJSONCredentials(params *credentialsParams) (map[string]string, error) {
+	out, err := json.Marshal(map[string]string{
+		"accessKeyId":     params.AccessKeyID,
+		"secretAccessKey": params.SecretAccessKey,
+		"endpoint":        params.Endpoint,
+		"region":          params.Region,
+		"bucketId":        params.BucketID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"credentials.json": string(out),
+	}, nil
+}
+
+func renderRcloneCredentials(params *credentialsParams) (map[string]string, error) {
+	var conf strings.Builder
+	fmt.Fprintf(&conf, "[%s]\ntype = s3\n", params.Name)
+	if params.Endpoint == "" {
+		conf.WriteString("provider = AWS\n")
+	} else {
+		fmt.Fprintf(&conf, "provider = Other\nendpoint = %s\n", params.Endpoint)
+	}
+	fmt.Fprintf(&conf, "access_key_id = %s\nsecret_access_key = %s\n", params.AccessKeyID, params.SecretAccessKey)
+	if params.Region != "" {
+		fmt.Fprintf(&conf, "region = %s\n", params.Region)
+	}
+	return map[string]string{
+		"rclone.conf": conf.String(),
+	}, nil
+}
+
+func renderS3cmdCredentials(params *credentialsParams) (map[string]string, error) {
+	var conf strings.Builder
+	fmt.Fprintf(&conf, "[default]\naccess_key = %s\nsecret_key = %s\n", params.AccessKeyID, params.SecretAccessKey)
+	if params.Region != "" {
+		fmt.Fprintf(&conf, "bucket_location = %s\n", params.Region)
+	}
+	if params.Endpoint != "" {
+		host, useHTTPS := params.Endpoint, true
+		if strings.Contains(params.Endpoint, "://") {
+			u, err := url.Parse(params.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid endpoint %q: %v", params.Endpoint, err)
+			}
+			host, useHTTPS = u.Host, u.Scheme != "http"
+		}
+		fmt.Fprintf(&conf, "host_base = %s\nhost_bucket = %s\n", host, host)
+		if useHTTPS {
+			conf.WriteString("use_https = True\n")
+		} else {
+			conf.WriteString("use_https = False\n")
+		}
+	}
+	return map[string]string{
+		".s3cfg": conf.String(),
+	}, nil
+}
+
+// validateCredentialsFormat checks that the credentials format is known
+func validateCredentialsFormat(format crdv1alpha1.CredentialsFormat) error {
+	if _, ok := credentialsRenderers[format]; !ok && format != crdv1alpha1.CredentialsFormatTemplate {
+		return fmt.Errorf("unknown credentials format %q", format)
+	}
+	return nil
+}
+
+// renderCredentialsTemplate renders each template of a credentialsTemplate into its secret key
+func renderCredentialsTemplate(templates map[string]string, params *credentialsParams) (map[string]string, error) {
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := make(map[string]string, len(templates))
+	for _, key := range keys {
+		tmpl, err := template.New(key).Option("missingkey=error").Funcs(policyTemplateFuncs).Parse(templates[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse credentials template %s: %v", key, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, params); err != nil {
+			return nil, fmt.Errorf("failed to render credentials template %s: %v", key, err)
+		}
+		data[key] = buf.String()
+	}
+	return data, nil
+}
+
+// validateCredentialsTemplate checks the secret keys of a credentialsTemplate
+// and renders the templates with sample values
+func validateCredentialsTemplate(templates map[string]string) error {
+	for key := range templates {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid secret key %q: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	_, err := renderCredentialsTemplate(templates, &credentialsParams{
+		AccessKeyID:     "access-key-id",
+		SecretAccessKey: "secret-access-key",
+		Endpoint:        "https://s3.example.com",
+		Region:          "region",
+		BucketID:        "bucket",
+		Namespace:       "namespace",
+		Name:            "name",
+	})
+	return err
+}
+
+// getSourceCredentials returns the raw access key of the PXBucketAccess
+func (ctrl *Controller) getSourceCredentials(ctx context.Context, pba *crdv1alpha1.PXBucketAccess) (string, string, error) {
+	source, err := ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Get(ctx, getCredentialsSourceName(pba), metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	return string(source.Data[accessKeyIDKey]), string(source.Data[secretAccessKeyKey]), nil
+}
+
+// writeSourceCredentials stores the raw access key of the PXBucketAccess
+func (ctrl *Controller) writeSourceCredentials(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, accessKeyID, secretAccessKey string) error {
+	_, err := ctrl.applySecretData(ctx, pba, getCredentialsSourceName(pba), credentialsKindSource, map[string]string{
+		accessKeyIDKey:     accessKeyID,
+		secretAccessKeyKey: secretAccessKey,
+	})
+	return err
+}
+
+// applySecretData creates the secret or replaces its data. The data is
+// written in a single request so that readers never see a partial update.
+// Existing secrets that are not owned by the PXBucketAccess are never
+// modified. A secret deleted by someone else is created again.
+func (ctrl *Controller) applySecretData(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, name, kind string, data map[string]string) (secretChange, error) {
+	secretData := make(map[string][]byte, len(data))
+	for k, v := range data {
+		secretData[k] = []byte(v)
+	}
+	dataHash := hashSecretData(secretData)
+
+	change := secretUnchanged
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := ctrl.getLiveSecret(ctx, pba, name)
+		if k8s_errors.IsNotFound(err) {
+			_, err = ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Create(
+				ctx,
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            name,
+						Namespace:       pba.Namespace,
+						Labels:          getSecretLabels(pba, kind),
+						Annotations:     map[string]string{credentialsHashKey: dataHash},
+						OwnerReferences: []metav1.OwnerReference{newBucketAccessOwnerReference(pba)},
+						Finalizers:      []string{accessSecretFinalizer},
+					},
+					Data: secretData,
+				},
+				metav1.CreateOptions{},
+			)
+			if err == nil {
+				change = secretCreated
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		ownershipChanged := setSecretOwnership(secret, pba, kind)
+		if !ownershipChanged && secret.Annotations[credentialsHashKey] == dataHash && reflect.DeepEqual(secret.Data, secretData) {
+			return nil
+		}
+		secretChange := secretUpdated
+		if isSecretDataModified(secret) {
+			secretChange = secretRestored
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[credentialsHashKey] = dataHash
+		secret.Data = secretData
+		secret.StringData = nil
+		_, err = ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		if err == nil {
+			change = secretChange
+		}
+		return err
+	})
+	return change, err
+}
+
+// getCredentialsParams returns the values rendered into the credentials secret of the PXBucketAccess
+func getCredentialsParams(pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, bucketID, accessKeyID, secretAccessKey string) *credentialsParams {
+	return &credentialsParams{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Endpoint:        pbclass.Parameters[endpointKey],
+		Region:          pbclass.Region,
+		BucketID:        bucketID,
+		Namespace:       pba.Namespace,
+		Name:            pba.Name,
+	}
+}
+
+// reconcileCredentialsSecret renders the credentials secret of a granted
+// PXBucketAccess from its source credentials and updates it if the format,
+// the key or the bucket details changed.
+func (ctrl *Controller) reconcileCredentialsSecret(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, pbaclass *crdv1alpha1.PXBucketAccessClass) (*crdv1alpha1.PXBucketAccess, error) {
+	accessKeyID, secretAccessKey, err := ctrl.getSourceCredentials(ctx, pba)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get source credentials: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonCredentialsSecretFailed, errMsg)
+		return pba, err
+	}
+
+	format := getCredentialsFormat(pba, pbaclass)
+	data, err := renderCredentials(format, pbaclass, getCredentialsParams(pba, pbclass, pba.Status.BucketId, accessKeyID, secretAccessKey))
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to render credentials secret: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonCredentialsSecretFailed, errMsg)
+		return pba, err
+	}
+
+	change, err := ctrl.applySecretData(ctx, pba, getCredentialsSecretName(pba), credentialsKindRendered, data)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to update credentials secret: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonCredentialsSecretFailed, errMsg)
+		return pba, err
+	}
+	ctrl.recordSecretRepair(pba, getCredentialsSecretName(pba), change)
+	if change == secretUpdated {
+		ctrl.eventRecorder.Event(pba, v1.EventTypeNormal, "CredentialsSecretUpdated", fmt.Sprintf("credentials secret %s rendered in format %s", getCredentialsSecretName(pba), format))
+	}
+
+	if pba.Status.CredentialsFormat != format || pba.Status.Phase != crdv1alpha1.BucketPhaseReady {
+		pba.Status.CredentialsFormat = format
+		bucketAccessStatusFields(pba).setPhase(crdv1alpha1.BucketPhaseReady, reasonAccessGranted, fmt.Sprintf("access granted to bucket %s", pba.Status.BucketId))
+		return ctrl.updateBucketAccessStatus(ctx, pba)
+	}
+	return pba, nil
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+)
+
+func TestRenderCredentials(t *testing.T) {
+	params := &credentialsParams{
+		AccessKeyID:     "AKIA",
+		SecretAccessKey: "secret",
+		Endpoint:        "http://fb.example.com:80",
+		Region:          "us-east-1",
+		BucketID:        "bucket",
+		Namespace:       "team-a",
+		Name:            "analytics",
+	}
+
+	data, err := renderCredentials(crdv1alpha1.CredentialsFormatAWSEnv, nil, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data["AWS_ACCESS_KEY_ID"] != "AKIA" || data["AWS_ENDPOINT_URL"] != params.Endpoint || data["AWS_REGION"] != "us-east-1" {
+		t.Errorf("unexpected AWS environment: %v", data)
+	}
+
+	data, err = renderCredentials(crdv1alpha1.CredentialsFormatJSON, nil, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc map[string]string
+	if err := json.Unmarshal([]byte(data["credentials.json"]), &doc); err != nil || doc["secretAccessKey"] != "secret" {
+		t.Errorf("unexpected JSON credentials %q: %v", data["credentials.json"], err)
+	}
+
+	data, err = renderCredentials(crdv1alpha1.CredentialsFormatS3cmd, nil, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(data[".s3cfg"], "host_base = fb.example.com:80\n") || !strings.Contains(data[".s3cfg"], "use_https = False\n") {
+		t.Errorf("unexpected s3cmd config: %q", data[".s3cfg"])
+	}
+
+	// The Template format needs a class with a template
+	if _, err := renderCredentials(crdv1alpha1.CredentialsFormatTemplate, nil, params); err == nil {
+		t.Errorf("expected error for Template format without a PXBucketAccessClass")
+	}
+	class := &crdv1alpha1.PXBucketAccessClass{
+		CredentialsTemplate: map[string]string{
+			"S3_URL": "s3://{{.AccessKeyID}}:{{.SecretAccessKey}}@{{.BucketID}}",
+		},
+	}
+	data, err = renderCredentials(crdv1alpha1.CredentialsFormatTemplate, class, params)
+	if err != nil || data["S3_URL"] != "s3://AKIA:secret@bucket" || len(data) != 1 {
+		t.Errorf("unexpected template credentials %v: %v", data, err)
+	}
+
+	if err := validateCredentialsTemplate(map[string]string{"bad key": "x"}); err == nil {
+		t.Errorf("expected error for invalid secret key")
+	}
+	if err := validateCredentialsTemplate(map[string]string{"key": "{{.Missing}}"}); err == nil {
+		t.Errorf("expected error for unknown template field")
+	}
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// IsDefaultBucketClassKey is a PXBucketClass annotation marking the class
+	// as the cluster wide default
+	IsDefaultBucketClassKey = commonObjectServiceKeyPrefix + "is-default-class"
+
+	// NamespaceDefaultBucketClassKey is a Namespace annotation naming the
+	// default PXBucketClass for claims and accesses in the namespace
+	NamespaceDefaultBucketClassKey = commonObjectServiceKeyPrefix + "default-bucket-class"
+)
+
+// DefaultBucketClassName returns the PXBucketClass to use for a claim or access
+// in the given namespace that does not reference a class. The namespace
+// annotation takes precedence over the cluster default. If several classes are
+// marked as cluster default, the most recently created one is used.
+func DefaultBucketClassName(classes []*crdv1alpha1.PXBucketClass, namespace *v1.Namespace) (string, error) {
+	if namespace != nil {
+		if name := namespace.Annotations[NamespaceDefaultBucketClassKey]; name != "" {
+			return name, nil
+		}
+	}
+
+	var defaultClass *crdv1alpha1.PXBucketClass
+	for _, class := range classes {
+		isDefault, _ := strconv.ParseBool(class.Annotations[IsDefaultBucketClassKey])
+		if !isDefault {
+			continue
+		}
+		if defaultClass != nil {
+			logrus.Warnf("multiple default PXBucketClasses found, using the newest of %s and %s", defaultClass.Name, class.Name)
+		}
+		if defaultClass == nil || defaultClass.CreationTimestamp.Before(&class.CreationTimestamp) {
+			defaultClass = class
+		}
+	}
+	if defaultClass == nil {
+		return "", fmt.Errorf("no default PXBucketClass is set")
+	}
+
+	return defaultClass.Name, nil
+}
+
+// getDefaultBucketClassName resolves the default PXBucketClass for the namespace
+func (ctrl *Controller) getDefaultBucketClassName(ctx context.Context, namespace string) (string, error) {
+	ns, err := ctrl.k8sClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	classes, err := ctrl.classLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+
+	return DefaultBucketClassName(classes, ns)
+}
+
+// bucketClassNamePatch returns a merge patch setting spec.bucketClassName
+func bucketClassNamePatch(name string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"bucketClassName": name,
+		},
+	})
+}
+
+// setDefaultBucketClaimClass fills in the default PXBucketClass of a PXBucketClaim
+// that does not reference a class.
+func (ctrl *Controller) setDefaultBucketClaimClass(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) (*crdv1alpha1.PXBucketClaim, error) {
+	name, err := ctrl.getDefaultBucketClassName(ctx, pbc.Namespace)
+	if err != nil {
+		return pbc, err
+	}
+	patch, err := bucketClassNamePatch(name)
+	if err != nil {
+		return pbc, err
+	}
+
+	logrus.WithContext(ctx).Infof("setting default bucketclass %s on bucketclaim %s/%s", name, pbc.Namespace, pbc.Name)
+	return ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Patch(ctx, pbc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// setDefaultBucketAccessClass fills in the default PXBucketClass of a PXBucketAccess
+// that does not reference a class.
+func (ctrl *Controller) setDefaultBucketAccessClass(ctx context.Context, pba *crdv1alpha1.PXBucketAccess) (*crdv1alpha1.PXBucketAccess, error) {
+	name, err := ctrl.getDefaultBucketClassName(ctx, pba.Namespace)
+	if err != nil {
+		return pba, err
+	}
+	patch, err := bucketClassNamePatch(name)
+	if err != nil {
+		return pba, err
+	}
+
+	logrus.WithContext(ctx).Infof("setting default bucketclass %s on bucketaccess %s/%s", name, pba.Namespace, pba.Name)
+	return ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace).Patch(ctx, pba.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefaultBucketClassName(t *testing.T) {
+	now := time.Now()
+	newClass := func(name string, isDefault string, created time.Time) *crdv1alpha1.PXBucketClass {
+		return &crdv1alpha1.PXBucketClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations: map[string]string{
+					IsDefaultBucketClassKey: isDefault,
+				},
+			},
+		}
+	}
+	namespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a",
+			Annotations: map[string]string{
+				NamespaceDefaultBucketClassKey: "team-a-class",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		classes   []*crdv1alpha1.PXBucketClass
+		namespace *v1.Namespace
+		expected  string
+	}{
+		{
+			name:     "no default",
+			classes:  []*crdv1alpha1.PXBucketClass{newClass("s3", "false", now)},
+			expected: "",
+		},
+		{
+			name:     "cluster default",
+			classes:  []*crdv1alpha1.PXBucketClass{newClass("s3", "false", now), newClass("fb", "true", now)},
+			expected: "fb",
+		},
+		{
+			name:     "newest of multiple cluster defaults",
+			classes:  []*crdv1alpha1.PXBucketClass{newClass("new", "true", now), newClass("old", "true", now.Add(-time.Hour))},
+			expected: "new",
+		},
+		{
+			name:      "namespace default overrides cluster default",
+			classes:   []*crdv1alpha1.PXBucketClass{newClass("fb", "true", now)},
+			namespace: namespace,
+			expected:  "team-a-class",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := DefaultBucketClassName(tc.classes, tc.namespace)
+			if tc.expected == "" {
+				if err == nil {
+					t.Fatalf("expected error, got class %s", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("expected class %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/client/clientset/versioned/fake"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	backupFinalizer = "backup.example.com/protect"
+	gitopsFinalizer = "gitops.example.com/managed"
+)
+
+func TestRemoveFinalizer(t *testing.T) {
+	testCases := []struct {
+		name       string
+		finalizers []string
+		remove     string
+		expected   []string
+	}{
+		{
+			name:       "only own finalizer",
+			finalizers: []string{bucketProvisionedFinalizer},
+			remove:     bucketProvisionedFinalizer,
+			expected:   []string{},
+		},
+		{
+			name:       "third-party finalizers are kept in order",
+			finalizers: []string{backupFinalizer, bucketProvisionedFinalizer, gitopsFinalizer},
+			remove:     bucketProvisionedFinalizer,
+			expected:   []string{backupFinalizer, gitopsFinalizer},
+		},
+		{
+			name:       "finalizer not present",
+			finalizers: []string{backupFinalizer},
+			remove:     accessGrantedFinalizer,
+			expected:   []string{backupFinalizer},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := removeFinalizer(tc.finalizers, tc.remove)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Fatalf("expected finalizers %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAddFinalizer(t *testing.T) {
+	actual := addFinalizer([]string{backupFinalizer}, accessGrantedFinalizer)
+	expected := []string{backupFinalizer, accessGrantedFinalizer}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected finalizers %v, got %v", expected, actual)
+	}
+
+	actual = addFinalizer(actual, accessGrantedFinalizer)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected finalizer to be added once, got %v", actual)
+	}
+}
+
+func TestSecretFinalizerPatchKeepsThirdPartyFinalizers(t *testing.T) {
+	meta := metav1.ObjectMeta{
+		Name:            "px-os-credentials-test",
+		ResourceVersion: "5",
+		Finalizers:      []string{accessSecretFinalizer, backupFinalizer},
+	}
+	patch, err := metadataMergePatch(meta, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = removeFinalizer(meta.Finalizers, accessSecretFinalizer)
+	})
+	if err != nil {
+		t.Fatalf("failed to build patch: %v", err)
+	}
+
+	var actual map[string]map[string]interface{}
+	if err := json.Unmarshal(patch, &actual); err != nil {
+		t.Fatalf("failed to decode patch %s: %v", patch, err)
+	}
+	expected := map[string]map[string]interface{}{
+		"metadata": {
+			"resourceVersion": "5",
+			"finalizers":      []interface{}{backupFinalizer},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected patch %v, got %v", expected, actual)
+	}
+}
+
+func TestRemoveBucketFinalizersKeepsThirdPartyFinalizers(t *testing.T) {
+	pbc := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "claim",
+			Namespace:  "default",
+			Finalizers: []string{backupFinalizer, bucketProvisionedFinalizer, gitopsFinalizer},
+		},
+	}
+	ctrl := &Controller{
+		k8sBucketClient: fake.NewSimpleClientset(pbc),
+	}
+
+	if err := ctrl.removeBucketFinalizers(context.TODO(), pbc); err != nil {
+		t.Fatalf("failed to remove bucket finalizers: %v", err)
+	}
+
+	actual, err := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.TODO(), pbc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get bucket claim: %v", err)
+	}
+	expected := []string{backupFinalizer, gitopsFinalizer}
+	if !reflect.DeepEqual(actual.Finalizers, expected) {
+		t.Fatalf("expected finalizers %v, got %v", expected, actual.Finalizers)
+	}
+}
+
+func TestRemoveAccessFinalizersKeepsThirdPartyFinalizers(t *testing.T) {
+	pba := &crdv1alpha1.PXBucketAccess{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "access",
+			Namespace:  "default",
+			Finalizers: []string{accessGrantedFinalizer, gitopsFinalizer},
+		},
+	}
+	ctrl := &Controller{
+		k8sBucketClient: fake.NewSimpleClientset(pba),
+	}
+
+	if err := ctrl.removeAccessFinalizers(context.TODO(), pba); err != nil {
+		t.Fatalf("failed to remove access finalizers: %v", err)
+	}
+
+	actual, err := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace).Get(context.TODO(), pba.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get bucket access: %v", err)
+	}
+	expected := []string{gitopsFinalizer}
+	if !reflect.DeepEqual(actual.Finalizers, expected) {
+		t.Fatalf("expected finalizers %v, got %v", expected, actual.Finalizers)
+	}
+}
+
+func TestPatchMetadataRetriesOnConflict(t *testing.T) {
+	stale := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "claim",
+			ResourceVersion: "1",
+			Finalizers:      []string{bucketProvisionedFinalizer},
+		},
+	}
+	latest := stale.DeepCopy()
+	latest.ResourceVersion = "2"
+	latest.Finalizers = append(latest.Finalizers, gitopsFinalizer)
+
+	var patches []string
+	patched, err := patchMetadata(stale, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = removeFinalizer(meta.Finalizers, bucketProvisionedFinalizer)
+	}, func(name string, patch []byte) (metav1.Object, error) {
+		patches = append(patches, string(patch))
+		if len(patches) == 1 {
+			return nil, k8s_errors.NewConflict(schema.GroupResource{Resource: "pxbucketclaims"}, name, errors.New("stale"))
+		}
+		updated := latest.DeepCopy()
+		updated.ResourceVersion = "3"
+		updated.Finalizers = []string{gitopsFinalizer}
+		return updated, nil
+	}, func(name string) (metav1.Object, error) {
+		return latest, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`{"metadata":{"finalizers":[],"resourceVersion":"1"}}`,
+		`{"metadata":{"finalizers":["gitops.example.com/managed"],"resourceVersion":"2"}}`,
+	}
+	if !reflect.DeepEqual(patches, expected) {
+		t.Errorf("expected patches %v, got %v", expected, patches)
+	}
+	if pbc := patched.(*crdv1alpha1.PXBucketClaim); pbc.ResourceVersion != "3" {
+		t.Errorf("expected patched object to be returned, got %+v", pbc.ObjectMeta)
+	}
+}
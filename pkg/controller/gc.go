@@ -0,0 +1,439 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/pkg/backend"
+	v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+// Garbage collection modes
+const (
+	// GCModeDisabled turns garbage collection off
+	GCModeDisabled = "disabled"
+	// GCModeReport only reports orphaned buckets and accounts
+	GCModeReport = "report"
+	// GCModeDelete deletes orphaned buckets and accounts after the grace period
+	GCModeDelete = "delete"
+)
+
+const (
+	// orphanBucketPrefix is the prefix of bucket names generated by the
+	// controller. Buckets named by a template are not collected.
+	orphanBucketPrefix = bucketIDPrefix
+	// orphanAccountPrefix is the prefix of the per namespace accounts
+	orphanAccountPrefix = "px-os-account-"
+
+	// clusterIDTagKey is the backend tag of the buckets and accounts created
+	// by the controller. Only the ones tagged with the ID of this cluster are
+	// collected, so that controllers of other clusters sharing a backend are
+	// never affected.
+	clusterIDTagKey = commonObjectServiceKeyPrefix + "cluster-id"
+	// clusterIDNamespace is the namespace whose UID identifies the cluster
+	clusterIDNamespace = "kube-system"
+
+	gcReportName = "px-object-controller-gc-report"
+	gcReportKey  = "report.json"
+)
+
+// Actions recorded for each orphan in the garbage collection report
+const (
+	gcActionReported          = "Reported"
+	gcActionWithinGracePeriod = "WithinGracePeriod"
+	gcActionDeleted           = "Deleted"
+	gcActionDeleteFailed      = "DeleteFailed"
+)
+
+// gcOrphan is a bucket or account on the backend that no PXBucketClaim,
+// PXBucket or PXBucketAccess refers to
+type gcOrphan struct {
+	Kind        string    `json:"kind"`
+	Name        string    `json:"name"`
+	BackendType string    `json:"backendType"`
+	Region      string    `json:"region,omitempty"`
+	Endpoint    string    `json:"endpoint,omitempty"`
+	Created     time.Time `json:"created"`
+	Action      string    `json:"action"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// gcReport is the result of a garbage collection run
+type gcReport struct {
+	Time        time.Time  `json:"time"`
+	ClusterID   string     `json:"clusterID"`
+	Mode        string     `json:"mode"`
+	GracePeriod string     `json:"gracePeriod"`
+	Orphans     []gcOrphan `json:"orphans"`
+	Errors      []string   `json:"errors,omitempty"`
+}
+
+// gcTarget is a backend endpoint whose buckets are checked for orphans
+type gcTarget struct {
+	backendType string
+	region      string
+	endpoint    string
+}
+
+// ValidateGCMode checks the garbage collection mode
+func ValidateGCMode(mode string) error {
+	switch mode {
+	case "", GCModeDisabled, GCModeReport, GCModeDelete:
+		return nil
+	}
+	return fmt.Errorf("garbage collection mode must be %s, %s or %s, got %q", GCModeDisabled, GCModeReport, GCModeDelete, mode)
+}
+
+// gcEnabled returns true if garbage collection runs in the given mode
+func gcEnabled(mode string) bool {
+	return mode == GCModeReport || mode == GCModeDelete
+}
+
+// getGCAction returns what garbage collection does with an orphan of the
+// given age. Orphans are only deleted once they are older than the grace
+// period, so that objects being provisioned are never collected.
+func getGCAction(mode string, created, now time.Time, gracePeriod time.Duration) string {
+	if now.Sub(created) < gracePeriod {
+		return gcActionWithinGracePeriod
+	}
+	if mode == GCModeDelete {
+		return gcActionDeleted
+	}
+	return gcActionReported
+}
+
+// getGCTargets returns the endpoints of the PXBucketClasses of the backends
+// with admin credentials configured
+func getGCTargets(classes []*crdv1alpha1.PXBucketClass, backends map[string]*backend.Client) []gcTarget {
+	seen := make(map[gcTarget]bool)
+	targets := make([]gcTarget, 0)
+	for _, class := range classes {
+		target := gcTarget{
+			backendType: class.Parameters[backendTypeKey],
+			region:      class.Region,
+			endpoint:    class.Parameters[endpointKey],
+		}
+		if _, ok := backends[target.backendType]; !ok || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		a, b := targets[i], targets[j]
+		if a.backendType != b.backendType {
+			return a.backendType < b.backendType
+		}
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+		return a.region < b.region
+	})
+	return targets
+}
+
+// getClusterID returns the UID of the kube-system namespace, which identifies
+// the buckets and accounts created by the controller of this cluster
+func (ctrl *Controller) getClusterID(ctx context.Context) (string, error) {
+	ctrl.clusterIDMu.Lock()
+	defer ctrl.clusterIDMu.Unlock()
+	if ctrl.clusterID != "" {
+		return ctrl.clusterID, nil
+	}
+	namespace, err := ctrl.k8sClient.CoreV1().Namespaces().Get(ctx, clusterIDNamespace, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster ID from namespace %s: %v", clusterIDNamespace, err)
+	}
+	ctrl.clusterID = string(namespace.UID)
+	return ctrl.clusterID, nil
+}
+
+// isGCCandidate returns true if a bucket or account with the given tags was
+// created by the controller of the cluster
+func isGCCandidate(tags map[string]string, clusterID string) bool {
+	return clusterID != "" && tags[clusterIDTagKey] == clusterID
+}
+
+// tagBucket tags a bucket created by the controller with the cluster ID.
+// Buckets without the tag are never garbage collected, so failures are
+// only logged.
+func (ctrl *Controller) tagBucket(ctx context.Context, backendType, name, region, endpoint string) {
+	backendClient, ok := ctrl.config.Backends[backendType]
+	if !ok {
+		return
+	}
+	clusterID, err := ctrl.getClusterID(ctx)
+	if err == nil {
+		err = backendClient.TagBucket(ctx, name, region, endpoint, map[string]string{clusterIDTagKey: clusterID})
+	}
+	if err != nil {
+		logrus.WithContext(ctx).Warnf("bucket %s will not be garbage collected: %v", name, err)
+	}
+}
+
+// tagAccount tags the account of a bucket access with the cluster ID.
+// Accounts are IAM users on the backends with tracked keys only.
+func (ctrl *Controller) tagAccount(ctx context.Context, backendType, accountID string) {
+	backendClient, ok := ctrl.config.Backends[backendType]
+	if !ok || !rotationDrivers[backendType] || accountID == "" {
+		return
+	}
+	clusterID, err := ctrl.getClusterID(ctx)
+	if err == nil {
+		err = backendClient.TagUser(ctx, accountID, map[string]string{clusterIDTagKey: clusterID})
+	}
+	if err != nil {
+		logrus.WithContext(ctx).Warnf("account %s will not be garbage collected: %v", accountID, err)
+	}
+}
+
+// getTrackedBuckets returns the IDs of the buckets referred to by a
+// PXBucketClaim, a PXBucket or a PXBucketAccess
+func getTrackedBuckets(claims []*crdv1alpha1.PXBucketClaim, buckets []*crdv1alpha1.PXBucket, accesses []*crdv1alpha1.PXBucketAccess) map[string]bool {
+	tracked := make(map[string]bool)
+	for _, pbc := range claims {
+		if pbc.Status != nil && pbc.Status.BucketID != "" {
+			tracked[pbc.Status.BucketID] = true
+		}
+		for _, name := range []string{pbc.Spec.ExistingBucketName, pbc.Spec.BucketName} {
+			if name != "" {
+				tracked[name] = true
+			}
+		}
+	}
+	for _, pb := range buckets {
+		tracked[pb.Name] = true
+		if pb.Spec.BucketID != "" {
+			tracked[pb.Spec.BucketID] = true
+		}
+	}
+	for _, pba := range accesses {
+		if pba.Spec.ExistingBucketId != "" {
+			tracked[pba.Spec.ExistingBucketId] = true
+		}
+		if pba.Status != nil && pba.Status.BucketId != "" {
+			tracked[pba.Status.BucketId] = true
+		}
+	}
+	return tracked
+}
+
+// getTrackedAccounts returns the accounts referred to by a PXBucketAccess.
+// The account of every namespace with a PXBucketAccess counts as tracked,
+// since a grant in progress has not recorded its account yet.
+func getTrackedAccounts(namespaces []v1.Namespace, accesses []*crdv1alpha1.PXBucketAccess) map[string]bool {
+	withAccesses := make(map[string]bool)
+	tracked := make(map[string]bool)
+	for _, pba := range accesses {
+		withAccesses[pba.Namespace] = true
+		if pba.Status != nil && pba.Status.AccountId != "" {
+			tracked[pba.Status.AccountId] = true
+		}
+	}
+	for i := range namespaces {
+		if withAccesses[namespaces[i].Name] {
+			tracked[getAccountName(&namespaces[i])] = true
+		}
+	}
+	return tracked
+}
+
+// collectGarbage finds buckets and accounts on the backends that were created
+// by the controller of this cluster but are no longer tracked, reports them and, in delete
+// mode, deletes the ones older than the grace period
+func (ctrl *Controller) collectGarbage() {
+	ctx := correlation.WithCorrelationContext(context.Background(), "px-object-controller/pkg/controller")
+	now := time.Now()
+	report := &gcReport{
+		Time:        now,
+		Mode:        ctrl.config.GCMode,
+		GracePeriod: ctrl.config.GCGracePeriod.String(),
+		Orphans:     make([]gcOrphan, 0),
+	}
+
+	// Listers are read before the backends so that objects created in
+	// between are covered by the grace period
+	claims, err := ctrl.bucketLister.List(labels.Everything())
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("garbage collection skipped, failed to list bucketclaims: %v", err)
+		return
+	}
+	buckets, err := ctrl.pxBucketLister.List(labels.Everything())
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("garbage collection skipped, failed to list buckets: %v", err)
+		return
+	}
+	accesses, err := ctrl.accessLister.List(labels.Everything())
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("garbage collection skipped, failed to list bucketaccesses: %v", err)
+		return
+	}
+	classes, err := ctrl.classLister.List(labels.Everything())
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("garbage collection skipped, failed to list bucketclasses: %v", err)
+		return
+	}
+	namespaces, err := ctrl.k8sClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("garbage collection skipped, failed to list namespaces: %v", err)
+		return
+	}
+
+	clusterID, err := ctrl.getClusterID(ctx)
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("garbage collection skipped: %v", err)
+		return
+	}
+	report.ClusterID = clusterID
+
+	trackedBuckets := getTrackedBuckets(claims, buckets, accesses)
+	seen := make(map[string]bool)
+	for _, target := range getGCTargets(classes, ctrl.config.Backends) {
+		list, err := ctrl.config.Backends[target.backendType].ListBuckets(ctx, target.region, target.endpoint)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to list buckets of %s at %q: %v", target.backendType, target.endpoint, err))
+			continue
+		}
+		for _, b := range list {
+			key := target.backendType + "/" + target.region + "/" + target.endpoint + "/" + b.Name
+			if !strings.HasPrefix(b.Name, orphanBucketPrefix) || trackedBuckets[b.Name] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			tags, err := ctrl.config.Backends[target.backendType].GetBucketTags(ctx, b.Name, target.region, target.endpoint)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+			if !isGCCandidate(tags, clusterID) {
+				continue
+			}
+			report.Orphans = append(report.Orphans, gcOrphan{
+				Kind:        "Bucket",
+				Name:        b.Name,
+				BackendType: target.backendType,
+				Region:      target.region,
+				Endpoint:    target.endpoint,
+				Created:     b.CreationDate,
+				Action:      getGCAction(ctrl.config.GCMode, b.CreationDate, now, ctrl.config.GCGracePeriod),
+			})
+		}
+	}
+
+	trackedAccounts := getTrackedAccounts(namespaces.Items, accesses)
+	backendTypes := make([]string, 0, len(ctrl.config.Backends))
+	for backendType := range ctrl.config.Backends {
+		backendTypes = append(backendTypes, backendType)
+	}
+	sort.Strings(backendTypes)
+	for _, backendType := range backendTypes {
+		// Accounts are IAM users on the backends with tracked keys
+		if !rotationDrivers[backendType] {
+			continue
+		}
+		users, err := ctrl.config.Backends[backendType].ListUsers(ctx, orphanAccountPrefix)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to list accounts of %s: %v", backendType, err))
+			continue
+		}
+		for name, created := range users {
+			if trackedAccounts[name] {
+				continue
+			}
+			tags, err := ctrl.config.Backends[backendType].GetUserTags(ctx, name)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+			if !isGCCandidate(tags, clusterID) {
+				continue
+			}
+			report.Orphans = append(report.Orphans, gcOrphan{
+				Kind:        "Account",
+				Name:        name,
+				BackendType: backendType,
+				Created:     created,
+				Action:      getGCAction(ctrl.config.GCMode, created, now, ctrl.config.GCGracePeriod),
+			})
+		}
+	}
+
+	for i := range report.Orphans {
+		orphan := &report.Orphans[i]
+		if orphan.Action == gcActionDeleted {
+			if err := ctrl.deleteOrphan(ctx, orphan); err != nil {
+				orphan.Action = gcActionDeleteFailed
+				orphan.Error = err.Error()
+			}
+		}
+		logrus.WithContext(ctx).Infof("garbage collection: %s %s of %s created %s: %s %s", strings.ToLower(orphan.Kind), orphan.Name, orphan.BackendType, orphan.Created.Format(time.RFC3339), orphan.Action, orphan.Error)
+	}
+	for _, msg := range report.Errors {
+		logrus.WithContext(ctx).Errorf("garbage collection: %s", msg)
+	}
+	logrus.WithContext(ctx).Infof("garbage collection found %d orphans", len(report.Orphans))
+
+	if err := ctrl.writeGCReport(ctx, report); err != nil {
+		logrus.WithContext(ctx).Errorf("failed to write garbage collection report: %v", err)
+	}
+}
+
+// deleteOrphan deletes an orphaned bucket or account. Buckets are deleted
+// through the SDK and are never cleared, so buckets with data are kept.
+func (ctrl *Controller) deleteOrphan(ctx context.Context, orphan *gcOrphan) error {
+	switch orphan.Kind {
+	case "Bucket":
+		_, err := ctrl.bucketClient.DeleteBucket(ctrl.setupContextFromValue(ctx, orphan.BackendType), &api.BucketDeleteRequest{
+			BucketId: orphan.Name,
+			Region:   orphan.Region,
+			Endpoint: orphan.Endpoint,
+		})
+		return err
+	case "Account":
+		return ctrl.config.Backends[orphan.BackendType].DeleteUser(ctx, orphan.Name)
+	}
+	return fmt.Errorf("unknown orphan kind %s", orphan.Kind)
+}
+
+// writeGCReport stores the report in a ConfigMap in the controller namespace
+func (ctrl *Controller) writeGCReport(ctx context.Context, report *gcReport) error {
+	if ctrl.config.Namespace == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	client := ctrl.k8sClient.CoreV1().ConfigMaps(ctrl.config.Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := client.Get(ctx, gcReportName, metav1.GetOptions{})
+		if k8s_errors.IsNotFound(err) {
+			_, err = client.Create(ctx, &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      gcReportName,
+					Namespace: ctrl.config.Namespace,
+					Labels:    map[string]string{managedByLabel: managedByValue},
+				},
+				Data: map[string]string{gcReportKey: string(data)},
+			}, metav1.CreateOptions{})
+			return err
+		} else if err != nil {
+			return err
+		}
+		cm.Data = map[string]string{gcReportKey: string(data)}
+		_, err = client.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
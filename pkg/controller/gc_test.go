@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/pkg/backend"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetGCAction(t *testing.T) {
+	now := time.Now()
+	grace := 24 * time.Hour
+
+	if action := getGCAction(GCModeDelete, now.Add(-time.Hour), now, grace); action != gcActionWithinGracePeriod {
+		t.Errorf("expected recent orphan to be within the grace period, got %s", action)
+	}
+	if action := getGCAction(GCModeDelete, now.Add(-48*time.Hour), now, grace); action != gcActionDeleted {
+		t.Errorf("expected old orphan to be deleted, got %s", action)
+	}
+	if action := getGCAction(GCModeReport, now.Add(-48*time.Hour), now, grace); action != gcActionReported {
+		t.Errorf("expected old orphan to be reported only, got %s", action)
+	}
+}
+
+func TestGetTrackedBuckets(t *testing.T) {
+	claims := []*crdv1alpha1.PXBucketClaim{
+		{Status: &crdv1alpha1.BucketClaimStatus{BucketID: "px-os-claim"}},
+		{Spec: crdv1alpha1.BucketClaimSpec{ExistingBucketName: "px-os-adopting"}},
+	}
+	buckets := []*crdv1alpha1.PXBucket{{ObjectMeta: metav1.ObjectMeta{Name: "px-os-retained"}}}
+	accesses := []*crdv1alpha1.PXBucketAccess{{Spec: crdv1alpha1.BucketAccessSpec{ExistingBucketId: "px-os-existing"}}}
+
+	tracked := getTrackedBuckets(claims, buckets, accesses)
+	for _, name := range []string{"px-os-claim", "px-os-adopting", "px-os-retained", "px-os-existing"} {
+		if !tracked[name] {
+			t.Errorf("expected bucket %s to be tracked", name)
+		}
+	}
+	if tracked["px-os-orphan"] {
+		t.Errorf("expected bucket px-os-orphan not to be tracked")
+	}
+}
+
+func TestGetTrackedAccounts(t *testing.T) {
+	namespaces := []v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "granting", UID: "uid-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "empty", UID: "uid-2"}},
+	}
+	// The grant is in progress and has not recorded its account yet
+	accesses := []*crdv1alpha1.PXBucketAccess{{ObjectMeta: metav1.ObjectMeta{Namespace: "granting"}}}
+
+	tracked := getTrackedAccounts(namespaces, accesses)
+	if !tracked["px-os-account-uid-1"] {
+		t.Errorf("expected account of a namespace with bucket accesses to be tracked")
+	}
+	if tracked["px-os-account-uid-2"] {
+		t.Errorf("expected account of a namespace without bucket accesses not to be tracked")
+	}
+}
+
+func TestGetGCTargets(t *testing.T) {
+	classes := []*crdv1alpha1.PXBucketClass{
+		{Region: "us-east-1", Parameters: map[string]string{backendTypeKey: "S3Driver"}},
+		{Region: "us-east-1", Parameters: map[string]string{backendTypeKey: "S3Driver"}},
+		{Parameters: map[string]string{backendTypeKey: "PureFBDriver", endpointKey: "fb.example.com"}},
+	}
+	targets := getGCTargets(classes, map[string]*backend.Client{"S3Driver": {}})
+	if len(targets) != 1 || targets[0].region != "us-east-1" {
+		t.Errorf("expected one S3Driver target, got %+v", targets)
+	}
+}
+
+func TestIsGCCandidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      map[string]string
+		clusterID string
+		expected  bool
+	}{
+		{name: "tagged by this cluster", tags: map[string]string{clusterIDTagKey: "uid-a"}, clusterID: "uid-a", expected: true},
+		{name: "tagged by another cluster", tags: map[string]string{clusterIDTagKey: "uid-b"}, clusterID: "uid-a"},
+		{name: "untagged", tags: map[string]string{}, clusterID: "uid-a"},
+		{name: "unknown cluster ID", tags: map[string]string{clusterIDTagKey: ""}, clusterID: ""},
+	}
+	for _, test := range tests {
+		if got := isGCCandidate(test.tags, test.clusterID); got != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
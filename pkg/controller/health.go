@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/portworx/px-object-controller/pkg/health"
+)
+
+const (
+	// cacheSyncTimeout is the time Run waits for the informer caches to sync
+	// before it gives up and reports the controller as unhealthy
+	cacheSyncTimeout = 5 * time.Minute
+	// workerStallTimeout is the time a worker may spend on a single item
+	// before the controller is reported as unhealthy
+	workerStallTimeout = 10 * time.Minute
+)
+
+// runState tracks the informer sync and the worker goroutines started by Run
+type runState struct {
+	mu sync.Mutex
+	// started is true once Run has been called, on the leader only
+	started bool
+	synced  bool
+	syncErr error
+	// workers is the number of worker goroutines that are expected to run
+	workers int
+	running int
+	stopped bool
+	// processing holds the start time of the items being processed
+	processing map[string]time.Time
+}
+
+func newRunState() *runState {
+	return &runState{processing: make(map[string]time.Time)}
+}
+
+func (s *runState) start(workers int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	s.workers = workers
+}
+
+func (s *runState) isStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+func (s *runState) setSynced(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = err == nil
+	s.syncErr = err
+}
+
+func (s *runState) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+// runWorker runs a worker goroutine until it returns
+func (s *runState) runWorker(worker func()) {
+	s.mu.Lock()
+	s.running++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running--
+	}()
+	worker()
+}
+
+// beginItem records that a worker picked up an item. The returned function
+// is called once the item is done.
+func (s *runState) beginItem(item string) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processing[item] = time.Now()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.processing, item)
+	}
+}
+
+// checkLiveness returns an error if the caches could not be synced, a worker
+// exited or a worker is stuck on an item
+func (s *runState) checkLiveness(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.syncErr != nil {
+		return s.syncErr
+	}
+	if !s.synced || s.stopped {
+		return nil
+	}
+	if s.running < s.workers {
+		return fmt.Errorf("%d of %d workers are running", s.running, s.workers)
+	}
+	for item, since := range s.processing {
+		if now.Sub(since) > workerStallTimeout {
+			return fmt.Errorf("worker processing %s for %v", item, now.Sub(since).Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// checkReadiness returns an error while the caches of a started controller
+// are not synced. Controllers waiting for leadership are ready.
+func (s *runState) checkReadiness() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started && !s.synced {
+		return fmt.Errorf("informer caches are not synced")
+	}
+	return nil
+}
+
+// AddHealthChecks adds the checks of the informers, the workers and the SDK
+// connection to the health checker
+func (ctrl *Controller) AddHealthChecks(checker *health.Checker) {
+	checker.AddLivenessCheck("controller", func(context.Context) error {
+		return ctrl.runState.checkLiveness(time.Now())
+	})
+	checker.AddReadinessCheck("informers", func(context.Context) error {
+		return ctrl.runState.checkReadiness()
+	})
+	checker.AddReadinessCheck("sdk", func(ctx context.Context) error {
+		// Only the leader uses the SDK
+		if !ctrl.runState.isStarted() {
+			return nil
+		}
+		return ctrl.bucketClient.Health(ctx)
+	})
+}
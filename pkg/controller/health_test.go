@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRunStateLiveness(t *testing.T) {
+	s := newRunState()
+	now := time.Now()
+	if err := s.checkReadiness(); err != nil {
+		t.Errorf("expected controller waiting for leadership to be ready: %v", err)
+	}
+
+	s.start(2)
+	if err := s.checkReadiness(); err == nil {
+		t.Errorf("expected controller with unsynced caches not to be ready")
+	}
+	if err := s.checkLiveness(now); err != nil {
+		t.Errorf("expected controller syncing caches to be alive: %v", err)
+	}
+
+	s.setSynced(nil)
+	if err := s.checkLiveness(now); err == nil {
+		t.Errorf("expected failure without running workers")
+	}
+	block := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go s.runWorker(func() { <-block })
+	}
+	defer close(block)
+	err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return s.checkLiveness(now) == nil, nil
+	})
+	if err != nil {
+		t.Errorf("expected controller with running workers to be alive")
+	}
+
+	done := s.beginItem("bucketclaim ns/claim")
+	if err := s.checkLiveness(now.Add(workerStallTimeout + time.Minute)); err == nil {
+		t.Errorf("expected failure of a worker stuck on an item")
+	}
+	done()
+	if err := s.checkLiveness(now.Add(workerStallTimeout + time.Minute)); err != nil {
+		t.Errorf("expected controller to be alive once the item is done: %v", err)
+	}
+}
+
+func TestRunStateSyncFailure(t *testing.T) {
+	errTest := errors.New("caches did not sync")
+	s := newRunState()
+	s.start(2)
+	s.setSynced(errTest)
+	s.stop()
+	if err := s.checkLiveness(time.Now()); err != errTest {
+		t.Errorf("expected cache sync failure, got %v", err)
+	}
+	if err := s.checkReadiness(); err == nil {
+		t.Errorf("expected controller with unsynced caches not to be ready")
+	}
+}
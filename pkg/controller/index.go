@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"reflect"
+	"strconv"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// bucketClassIndex indexes PXBucketClaims and PXBucketAccesses by spec.bucketClassName
+	bucketClassIndex = "bucketClassName"
+
+	// bucketClaimIndex indexes PXBucketAccesses by the namespaced name of their PXBucketClaim
+	bucketClaimIndex = "bucketClaim"
+
+	// bucketIDIndex indexes PXBucketClaims by status.bucketId
+	bucketIDIndex = "bucketId"
+
+	// bucketNameIndex indexes PXBucketClaims by spec.bucketName
+	bucketNameIndex = "bucketName"
+
+	// bucketAccessClassIndex indexes PXBucketAccesses by spec.bucketAccessClassName
+	bucketAccessClassIndex = "bucketAccessClassName"
+)
+
+// bucketClassIndexFunc returns the PXBucketClass referenced by a PXBucketClaim or PXBucketAccess.
+// Objects without a class are indexed under the empty name so that they can be
+// found when a default class is set.
+func bucketClassIndexFunc(obj interface{}) ([]string, error) {
+	switch o := obj.(type) {
+	case *crdv1alpha1.PXBucketClaim:
+		return []string{o.Spec.BucketClassName}, nil
+	case *crdv1alpha1.PXBucketAccess:
+		return []string{o.Spec.BucketClassName}, nil
+	}
+	return []string{}, nil
+}
+
+// bucketClaimIndexFunc returns the namespaced name of the PXBucketClaim referenced by a PXBucketAccess
+func bucketClaimIndexFunc(obj interface{}) ([]string, error) {
+	if access, ok := obj.(*crdv1alpha1.PXBucketAccess); ok && access.Spec.BucketClaimName != "" {
+		return []string{access.Namespace + "/" + access.Spec.BucketClaimName}, nil
+	}
+	return []string{}, nil
+}
+
+// bucketAccessClassIndexFunc returns the PXBucketAccessClass referenced by a PXBucketAccess
+func bucketAccessClassIndexFunc(obj interface{}) ([]string, error) {
+	if access, ok := obj.(*crdv1alpha1.PXBucketAccess); ok && access.Spec.BucketAccessClassName != "" {
+		return []string{access.Spec.BucketAccessClassName}, nil
+	}
+	return []string{}, nil
+}
+
+// bucketIDIndexFunc returns the bucket ID provisioned for a PXBucketClaim
+func bucketIDIndexFunc(obj interface{}) ([]string, error) {
+	if pbc, ok := obj.(*crdv1alpha1.PXBucketClaim); ok && pbc.Status != nil && pbc.Status.BucketID != "" {
+		return []string{pbc.Status.BucketID}, nil
+	}
+	return []string{}, nil
+}
+
+// bucketNameIndexFunc returns the PXBucket a PXBucketClaim binds to
+func bucketNameIndexFunc(obj interface{}) ([]string, error) {
+	if pbc, ok := obj.(*crdv1alpha1.PXBucketClaim); ok && pbc.Spec.BucketName != "" {
+		return []string{pbc.Spec.BucketName}, nil
+	}
+	return []string{}, nil
+}
+
+// enqueueBucketObjectClaims adds the PXBucketClaim bound to the given PXBucket
+// and all PXBucketClaims binding to it through spec.bucketName to the bucket
+// work queue, so that claims waiting for the PXBucket are retried as soon as
+// it is created, released or deleted.
+func (ctrl *Controller) enqueueBucketObjectClaims(obj interface{}) {
+	if unknown, ok := obj.(cache.DeletedFinalStateUnknown); ok && unknown.Obj != nil {
+		obj = unknown.Obj
+	}
+	pb, ok := obj.(*crdv1alpha1.PXBucket)
+	if !ok {
+		return
+	}
+
+	if pb.Spec.ClaimRef != nil && pb.Spec.ClaimRef.Name != "" {
+		ctrl.bucketQueue.Add(pb.Spec.ClaimRef.Namespace + "/" + pb.Spec.ClaimRef.Name)
+	}
+	claims, err := ctrl.bucketIndexer.ByIndex(bucketNameIndex, pb.Name)
+	if err != nil {
+		logrus.Errorf("failed to list PXBucketClaims for PXBucket %s: %v", pb.Name, err)
+		return
+	}
+	for _, claim := range claims {
+		ctrl.enqueueBucketWork(claim)
+	}
+}
+
+// enqueueClassDependents adds all PXBucketClaims and PXBucketAccesses
+// referencing the given PXBucketClass to their work queues.
+func (ctrl *Controller) enqueueClassDependents(obj interface{}) {
+	if unknown, ok := obj.(cache.DeletedFinalStateUnknown); ok && unknown.Obj != nil {
+		obj = unknown.Obj
+	}
+	class, ok := obj.(*crdv1alpha1.PXBucketClass)
+	if !ok {
+		return
+	}
+
+	classNames := []string{class.Name}
+	if isDefault, _ := strconv.ParseBool(class.Annotations[IsDefaultBucketClassKey]); isDefault {
+		// Objects without a class pick up the new default
+		classNames = append(classNames, "")
+	}
+
+	for _, className := range classNames {
+		buckets, err := ctrl.bucketIndexer.ByIndex(bucketClassIndex, className)
+		if err != nil {
+			logrus.Errorf("failed to list bucketclaims for bucketclass %s: %v", class.Name, err)
+		}
+		for _, bucket := range buckets {
+			ctrl.enqueueBucketWork(bucket)
+		}
+
+		accesses, err := ctrl.accessIndexer.ByIndex(bucketClassIndex, className)
+		if err != nil {
+			logrus.Errorf("failed to list bucketaccesses for bucketclass %s: %v", class.Name, err)
+		}
+		for _, access := range accesses {
+			ctrl.enqueueAccessWork(access)
+		}
+	}
+}
+
+// enqueueClaimDependents adds all PXBucketAccesses referencing the given
+// PXBucketClaim to the access work queue once the claim is provisioned.
+func (ctrl *Controller) enqueueClaimDependents(oldObj, newObj interface{}) {
+	oldClaim, ok := oldObj.(*crdv1alpha1.PXBucketClaim)
+	if !ok {
+		return
+	}
+	newClaim, ok := newObj.(*crdv1alpha1.PXBucketClaim)
+	if !ok {
+		return
+	}
+	if isBucketProvisioned(oldClaim) || !isBucketProvisioned(newClaim) {
+		return
+	}
+
+	accesses, err := ctrl.accessIndexer.ByIndex(bucketClaimIndex, newClaim.Namespace+"/"+newClaim.Name)
+	if err != nil {
+		logrus.Errorf("failed to list bucketaccesses for bucketclaim %s/%s: %v", newClaim.Namespace, newClaim.Name, err)
+	}
+	for _, access := range accesses {
+		ctrl.enqueueAccessWork(access)
+	}
+}
+
+// needsReconcile returns false for updates that only changed the status of a
+// PXBucketClaim or PXBucketAccess. The controller writes the status itself,
+// also after failed attempts, so reconciling those updates would bypass the
+// backoff of the work queue. Periodic resyncs are always reconciled.
+func needsReconcile(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return true
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return true
+	}
+	if oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		return true
+	}
+	return oldMeta.GetGeneration() != newMeta.GetGeneration() ||
+		!reflect.DeepEqual(oldMeta.GetFinalizers(), newMeta.GetFinalizers()) ||
+		!reflect.DeepEqual(oldMeta.GetAnnotations(), newMeta.GetAnnotations()) ||
+		!oldMeta.GetDeletionTimestamp().Equal(newMeta.GetDeletionTimestamp())
+}
+
+func isBucketProvisioned(pbc *crdv1alpha1.PXBucketClaim) bool {
+	return pbc.Status != nil && pbc.Status.Provisioned
+}
+
+// enqueueAccessClassDependents adds all PXBucketAccesses referencing the given
+// PXBucketAccessClass to the access work queue so that their credentials
+// secrets are rendered again.
+func (ctrl *Controller) enqueueAccessClassDependents(obj interface{}) {
+	if unknown, ok := obj.(cache.DeletedFinalStateUnknown); ok && unknown.Obj != nil {
+		obj = unknown.Obj
+	}
+	accessClass, ok := obj.(*crdv1alpha1.PXBucketAccessClass)
+	if !ok {
+		return
+	}
+
+	accesses, err := ctrl.accessIndexer.ByIndex(bucketAccessClassIndex, accessClass.Name)
+	if err != nil {
+		logrus.Errorf("failed to list bucketaccesses for bucketaccessclass %s: %v", accessClass.Name, err)
+	}
+	for _, access := range accesses {
+		ctrl.enqueueAccessWork(access)
+	}
+}
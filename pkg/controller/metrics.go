@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/pkg/metrics"
+	"github.com/portworx/px-object-controller/pkg/tracing"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// observeOperation runs a backend operation of the reconcile loops in a span
+// and records its duration and result
+func observeOperation(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, operation)
+	err := fn(ctx)
+	span.End(err)
+	metrics.ObserveOperation(operation, time.Since(start), err)
+	return err
+}
+
+// countObjects returns the number of PXBucketClaims and PXBucketAccesses by
+// phase and class. Objects without a status yet are counted as Pending.
+func (ctrl *Controller) countObjects() (map[metrics.ObjectKey]int, map[metrics.ObjectKey]int, error) {
+	claims, err := ctrl.bucketLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	accesses, err := ctrl.accessLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claimCounts := make(map[metrics.ObjectKey]int)
+	for _, pbc := range claims {
+		phase := string(crdv1alpha1.BucketPhasePending)
+		if pbc.Status != nil && pbc.Status.Phase != "" {
+			phase = string(pbc.Status.Phase)
+		}
+		claimCounts[metrics.ObjectKey{Phase: phase, Class: pbc.Spec.BucketClassName}]++
+	}
+	accessCounts := make(map[metrics.ObjectKey]int)
+	for _, pba := range accesses {
+		phase := string(crdv1alpha1.BucketPhasePending)
+		if pba.Status != nil && pba.Status.Phase != "" {
+			phase = string(pba.Status.Phase)
+		}
+		accessCounts[metrics.ObjectKey{Phase: phase, Class: pba.Spec.BucketClassName}]++
+	}
+	return claimCounts, accessCounts, nil
+}
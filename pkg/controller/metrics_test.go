@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	bucketlisters "github.com/portworx/px-object-controller/client/listers/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCountObjects(t *testing.T) {
+	claimIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	accessIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pbc := range []*crdv1alpha1.PXBucketClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ready-1", Namespace: "ns"},
+			Spec:       crdv1alpha1.BucketClaimSpec{BucketClassName: "s3"},
+			Status:     &crdv1alpha1.BucketClaimStatus{Phase: crdv1alpha1.BucketPhaseReady},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ready-2", Namespace: "ns"},
+			Spec:       crdv1alpha1.BucketClaimSpec{BucketClassName: "s3"},
+			Status:     &crdv1alpha1.BucketClaimStatus{Phase: crdv1alpha1.BucketPhaseReady},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "ns"},
+			Spec:       crdv1alpha1.BucketClaimSpec{BucketClassName: "s3"},
+		},
+	} {
+		if err := claimIndexer.Add(pbc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := accessIndexer.Add(&crdv1alpha1.PXBucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "ns"},
+		Spec:       crdv1alpha1.BucketAccessSpec{BucketClassName: "fb"},
+		Status:     &crdv1alpha1.BucketAccessStatus{Phase: crdv1alpha1.BucketPhaseFailed},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := &Controller{
+		bucketLister: bucketlisters.NewPXBucketClaimLister(claimIndexer),
+		accessLister: bucketlisters.NewPXBucketAccessLister(accessIndexer),
+	}
+	claims, accesses, err := ctrl.countObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := claims[metrics.ObjectKey{Phase: "Ready", Class: "s3"}]; n != 2 {
+		t.Errorf("expected 2 ready claims, got %d", n)
+	}
+	if n := claims[metrics.ObjectKey{Phase: "Pending", Class: "s3"}]; n != 1 {
+		t.Errorf("expected claim without status to be counted as pending, got %d", n)
+	}
+	if n := accesses[metrics.ObjectKey{Phase: "Failed", Class: "fb"}]; n != 1 {
+		t.Errorf("expected 1 failed access, got %d", n)
+	}
+}
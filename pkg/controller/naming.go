@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"text/template"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+)
+
+const (
+	// bucketNameTemplateKey is a PXBucketClass parameter holding a Go template
+	// for the names of provisioned buckets, such as
+	// "{{.Namespace}}-{{.Name}}-{{.ShortUID}}".
+	bucketNameTemplateKey = commonObjectServiceKeyPrefix + "bucket-name-template"
+
+	// bucketIDPrefix is the prefix of the bucket names generated by the
+	// controller
+	bucketIDPrefix = "px-os-"
+
+	shortUIDLength = 8
+)
+
+// bucketNameRegexp matches bucket names that are valid for S3 virtual hosted
+// style addressing: lowercase letters, digits and hyphens, starting and
+// ending with a letter or digit.
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*[a-z0-9]$`)
+
+// bucketNameParams are the values available to a bucket name template
+type bucketNameParams struct {
+	// Namespace is the namespace of the PXBucketClaim
+	Namespace string
+	// Name is the name of the PXBucketClaim
+	Name string
+	// UID is the UID of the PXBucketClaim
+	UID string
+	// ShortUID is the first 8 characters of the UID of the PXBucketClaim
+	ShortUID string
+	// BucketClassName is the name of the PXBucketClass
+	BucketClassName string
+}
+
+func newBucketNameParams(namespace, name, uid, className string) *bucketNameParams {
+	shortUID := strings.ReplaceAll(uid, "-", "")
+	if len(shortUID) > shortUIDLength {
+		shortUID = shortUID[:shortUIDLength]
+	}
+	return &bucketNameParams{
+		Namespace:       namespace,
+		Name:            name,
+		UID:             uid,
+		ShortUID:        shortUID,
+		BucketClassName: className,
+	}
+}
+
+// renderBucketName renders the bucket name template with the given values and validates the result
+func renderBucketName(nameTemplate string, params *bucketNameParams) (string, error) {
+	tmpl, err := template.New("bucketName").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %v", bucketNameTemplateKey, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", fmt.Errorf("failed to render %s: %v", bucketNameTemplateKey, err)
+	}
+
+	name := buf.String()
+	if err := validateBucketName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// validateBucketName checks the S3 bucket naming rules. Dots are rejected
+// since they break TLS for virtual hosted style addressing.
+func validateBucketName(name string) error {
+	if len(name) < 3 || len(name) > 63 {
+		return fmt.Errorf("bucket name %q must be between 3 and 63 characters long", name)
+	}
+	if !bucketNameRegexp.MatchString(name) {
+		return fmt.Errorf("bucket name %q must consist of lowercase letters, digits and hyphens and must start and end with a letter or digit", name)
+	}
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("bucket name %q must not be formatted as an IP address", name)
+	}
+	if strings.HasPrefix(name, "xn--") {
+		return fmt.Errorf("bucket name %q must not start with xn--", name)
+	}
+	if strings.HasSuffix(name, "-s3alias") || strings.HasSuffix(name, "--ol-s3") {
+		return fmt.Errorf("bucket name %q must not end with a reserved suffix", name)
+	}
+	return nil
+}
+
+// validateBucketNameTemplate renders the template with sample values to catch
+// templates that can never produce a valid bucket name.
+func validateBucketNameTemplate(nameTemplate string) error {
+	_, err := renderBucketName(nameTemplate, newBucketNameParams("namespace", "name", "00000000-0000-0000-0000-000000000000", "class"))
+	return err
+}
+
+// getBucketID returns the backend bucket name for a new PXBucketClaim. Once
+// recorded in the claim status the name never changes.
+func getBucketID(pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) (string, error) {
+	if pbc.Status != nil && pbc.Status.BucketID != "" {
+		return pbc.Status.BucketID, nil
+	}
+
+	nameTemplate, ok := pbclass.Parameters[bucketNameTemplateKey]
+	if !ok {
+		return fmt.Sprintf("%s%s", bucketIDPrefix, pbc.GetUID()), nil
+	}
+	return renderBucketName(nameTemplate, newBucketNameParams(pbc.Namespace, pbc.Name, string(pbc.UID), pbclass.Name))
+}
+
+// isNewTemplatedBucketID returns true if the bucket name of the PXBucketClaim
+// is rendered from a template and not yet recorded in its status. Unlike the
+// generated names, which contain the claim UID, such a name may match a bucket
+// that already exists on the backend.
+func isNewTemplatedBucketID(pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) bool {
+	if pbc.Status != nil && pbc.Status.BucketID != "" {
+		return false
+	}
+	_, ok := pbclass.Parameters[bucketNameTemplateKey]
+	return ok
+}
+
+// checkBucketNotExists returns an error if the bucket already exists on the
+// backend. The S3 driver reports buckets that the account already owns as
+// created, so a claim would silently take over an untracked bucket. Without
+// admin credentials the existence cannot be checked and the name must keep
+// the prefix of the controller.
+func (ctrl *Controller) checkBucketNotExists(ctx context.Context, pbclass *crdv1alpha1.PXBucketClass, bucketID string) error {
+	backendType := pbclass.Parameters[backendTypeKey]
+	backendClient, ok := ctrl.config.Backends[backendType]
+	if !ok {
+		if strings.HasPrefix(bucketID, bucketIDPrefix) {
+			return nil
+		}
+		return fmt.Errorf("bucket name %s must start with %s since no admin credentials are configured for backend %s to check that the bucket does not exist",
+			bucketID, bucketIDPrefix, backendType)
+	}
+
+	exists, err := backendClient.BucketExists(ctx, bucketID, pbclass.Region, pbclass.Parameters[endpointKey])
+	if err != nil {
+		return fmt.Errorf("failed to check whether bucket %s exists: %v", bucketID, err)
+	}
+	if exists {
+		return fmt.Errorf("bucket %s already exists on the backend. Use spec.existingBucketName to adopt it", bucketID)
+	}
+	return nil
+}
+
+// checkBucketIDUnused returns an error if the bucket belongs to a PXBucketClaim
+// other than pbc or to a PXBucket that is not bound to pbc
+func (ctrl *Controller) checkBucketIDUnused(pbc *crdv1alpha1.PXBucketClaim, bucketID string) error {
+	objs, err := ctrl.bucketIndexer.ByIndex(bucketIDIndex, bucketID)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		other, ok := obj.(*crdv1alpha1.PXBucketClaim)
+		if !ok || other.UID == pbc.UID {
+			continue
+		}
+		return fmt.Errorf("bucket %s already belongs to PXBucketClaim %s/%s", bucketID, other.Namespace, other.Name)
+	}
+
+	pbs, err := ctrl.getPXBucketsByID(bucketID)
+	if err != nil {
+		return err
+	}
+	for _, pb := range pbs {
+		if !isBucketBoundTo(pb, pbc) {
+			return fmt.Errorf("bucket %s is managed by PXBucket %s and can only be claimed through spec.bucketName", bucketID, pb.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetBucketID(t *testing.T) {
+	pbc := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "images",
+			Namespace: "team-a",
+			UID:       "3f2b8c1d-aaaa-bbbb-cccc-000000000000",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		status   *crdv1alpha1.BucketClaimStatus
+		expected string
+		valid    bool
+	}{
+		{
+			name:     "default name",
+			expected: "px-os-3f2b8c1d-aaaa-bbbb-cccc-000000000000",
+			valid:    true,
+		},
+		{
+			name:     "template",
+			template: "{{.Namespace}}-{{.Name}}-{{.ShortUID}}",
+			expected: "team-a-images-3f2b8c1d",
+			valid:    true,
+		},
+		{
+			name:     "name recorded in status wins",
+			template: "{{.Namespace}}-{{.Name}}-{{.ShortUID}}",
+			status:   &crdv1alpha1.BucketClaimStatus{BucketID: "px-os-old"},
+			expected: "px-os-old",
+			valid:    true,
+		},
+		{
+			name:     "dots are rejected",
+			template: "{{.Namespace}}.{{.Name}}",
+			valid:    false,
+		},
+		{
+			name:     "uppercase is rejected",
+			template: "Team-{{.Name}}",
+			valid:    false,
+		},
+		{
+			name:     "unknown field is rejected",
+			template: "{{.Team}}-{{.Name}}",
+			valid:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pbclass := &crdv1alpha1.PXBucketClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "class"},
+				Parameters: map[string]string{},
+			}
+			if tc.template != "" {
+				pbclass.Parameters[bucketNameTemplateKey] = tc.template
+			}
+			claim := pbc.DeepCopy()
+			claim.Status = tc.status
+
+			actual, err := getBucketID(claim, pbclass)
+			if !tc.valid {
+				if err == nil {
+					t.Fatalf("expected error, got bucket name %s", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("expected bucket name %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateBucketName(t *testing.T) {
+	for _, name := range []string{"ab", "-bucket", "bucket-", "192.168.1.1", "xn--bucket", "bucket-s3alias", "my_bucket"} {
+		if err := validateBucketName(name); err == nil {
+			t.Fatalf("expected bucket name %q to be invalid", name)
+		}
+	}
+	for _, name := range []string{"abc", "team-a-images-3f2b8c1d", "px-os-3f2b8c1d-aaaa-bbbb-cccc-000000000000"} {
+		if err := validateBucketName(name); err != nil {
+			t.Fatalf("expected bucket name %q to be valid: %v", name, err)
+		}
+	}
+}
+
+func TestCheckBucketNotExistsWithoutAdminCredentials(t *testing.T) {
+	ctrl := &Controller{config: &Config{}}
+	pbclass := &crdv1alpha1.PXBucketClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "class"},
+		Parameters: map[string]string{
+			backendTypeKey:        "S3Driver",
+			bucketNameTemplateKey: "{{.Namespace}}-{{.Name}}",
+		},
+	}
+
+	if err := ctrl.checkBucketNotExists(context.Background(), pbclass, "px-os-team-a-images"); err != nil {
+		t.Errorf("expected name with the controller prefix to be allowed, got %v", err)
+	}
+	if err := ctrl.checkBucketNotExists(context.Background(), pbclass, "team-a-images"); err == nil {
+		t.Errorf("expected name without the controller prefix to be rejected")
+	}
+}
+
+func TestIsNewTemplatedBucketID(t *testing.T) {
+	templated := &crdv1alpha1.PXBucketClass{
+		Parameters: map[string]string{bucketNameTemplateKey: "{{.Namespace}}-{{.Name}}"},
+	}
+	generated := &crdv1alpha1.PXBucketClass{}
+
+	if !isNewTemplatedBucketID(&crdv1alpha1.PXBucketClaim{}, templated) {
+		t.Errorf("expected new templated name to be checked")
+	}
+	if isNewTemplatedBucketID(&crdv1alpha1.PXBucketClaim{}, generated) {
+		t.Errorf("expected generated name not to be checked")
+	}
+	recorded := &crdv1alpha1.PXBucketClaim{Status: &crdv1alpha1.BucketClaimStatus{BucketID: "team-a-images"}}
+	if isNewTemplatedBucketID(recorded, templated) {
+		t.Errorf("expected recorded name not to be checked again")
+	}
+}
@@ -4,16 +4,20 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/api/server/sdk"
 	"github.com/libopenstorage/openstorage/pkg/grpcserver"
-	"github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
 	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 
 	"github.com/portworx/px-object-controller/pkg/utils"
-	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -38,34 +42,60 @@ var allowedDrivers = map[string]bool{
 func (ctrl *Controller) deleteBucket(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) error {
 
 	if pbc.Status == nil || !pbc.Status.Provisioned {
+		// Provisioned is written right after the bucket is created and is the
+		// only record that the controller created it. The bucket of a claim
+		// that is not provisioned may belong to someone else.
 		logrus.WithContext(ctx).Infof("bucket not yet provisioned. skipping backened delete")
-		ctrl.bucketStore.Delete(pbc)
+		// A bind may have been interrupted before the claim status was written
+		if pbc.Spec.BucketName != "" {
+			if err := ctrl.releaseBucketObject(ctx, pbc, pbc.Spec.BucketName); err != nil {
+				return err
+			}
+		}
+		err := ctrl.removeBucketFinalizers(ctx, pbc)
+		if err != nil {
+			return err
+		}
+
+		ctrl.storeBucketDelete(pbc)
 		return nil
 	}
 
+	if cond := meta.FindStatusCondition(pbc.Status.Conditions, crdv1alpha1.ConditionDeleting); cond == nil || cond.Reason != reasonDeleting {
+		pbc, _ = ctrl.setBucketClaimPhase(ctx, pbc, crdv1alpha1.BucketPhaseDeleting, reasonDeleting, "bucket claim is being deleted")
+	}
+
 	// Issue delete if provisioned and deletionPolicy is delete
 	if pbc.Status.DeletionPolicy == crdv1alpha1.PXBucketClaimRetain {
 		logrus.WithContext(ctx).Infof("skipping delete bucket as deletionPolicy was retain")
 
+		if pbc.Status.BucketName != "" {
+			if err := ctrl.releaseBucketObject(ctx, pbc, pbc.Status.BucketName); err != nil {
+				errMsg := fmt.Sprintf("failed to release bucket %s: %v", pbc.Status.BucketName, err)
+				logrus.WithContext(ctx).Errorf(errMsg)
+				ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "DeleteBucketError", errMsg)
+				ctrl.recordBucketClaimError(ctx, pbc, reasonDeleteBucketFailed, errMsg)
+				return err
+			}
+		}
+
 		err := ctrl.removeBucketFinalizers(ctx, pbc)
 		if err != nil {
 			errMsg := fmt.Sprintf("bucket claim %s/%s remove finalizer failed: %v", pbc.Namespace, pbc.Name, err)
 			logrus.WithContext(ctx).Errorf(errMsg)
 			ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "DeleteBucketError", errMsg)
+			ctrl.recordBucketClaimError(ctx, pbc, reasonDeleteBucketFailed, errMsg)
 			return err
 		}
 
-		ctrl.bucketStore.Delete(pbc)
+		ctrl.storeBucketDelete(pbc)
 		return nil
 	}
 
-	clearBucket := false
-	if clearBucketVal, ok := pbc.Annotations[clearBucketKey]; ok {
-		var err error
-		clearBucket, err = strconv.ParseBool(clearBucketVal)
-		if err != nil {
-			logrus.Errorf("invalid value %s for %s, defaulting to false: %v", clearBucketVal, clearBucketKey, err)
-		}
+	clearBucket := pbc.Status.ClearBucket
+	// Claims provisioned by older versions record clear-bucket as an annotation
+	if clearBucketVal, ok := pbc.Annotations[clearBucketKey]; ok && !clearBucket {
+		clearBucket = parseClearBucket(clearBucketVal)
 	}
 
 	// Provisioned and deletionPolicy is delete. Delete the bucket here.
@@ -79,57 +109,142 @@ func (ctrl *Controller) deleteBucket(ctx context.Context, pbc *crdv1alpha1.PXBuc
 		errMsg := fmt.Sprintf("delete bucket %s failed: %v", pbc.Name, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "DeleteBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonDeleteBucketFailed, errMsg)
 		return err
 	}
 
+	if pbc.Status.BucketName != "" {
+		if err := ctrl.deleteBucketObject(ctx, pbc, pbc.Status.BucketName); err != nil {
+			errMsg := fmt.Sprintf("failed to delete bucket object %s: %v", pbc.Status.BucketName, err)
+			logrus.WithContext(ctx).Errorf(errMsg)
+			ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "DeleteBucketError", errMsg)
+			ctrl.recordBucketClaimError(ctx, pbc, reasonDeleteBucketFailed, errMsg)
+			return err
+		}
+	}
+
 	err = ctrl.removeBucketFinalizers(ctx, pbc)
 	if err != nil {
 		errMsg := fmt.Sprintf("bucket claim %s/%s remove finalizer failed: %v", pbc.Namespace, pbc.Name, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "DeleteBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonDeleteBucketFailed, errMsg)
 		return err
 	}
 
-	ctrl.bucketStore.Delete(pbc)
+	ctrl.storeBucketDelete(pbc)
 	logrus.WithContext(ctx).Infof("bucket %q deleted", pbc.Name)
 
 	return nil
 }
 
+// isBackendNotFound returns true if an SDK call failed because the bucket or
+// account no longer exists on the backend
+func isBackendNotFound(err error) bool {
+	if status.Code(err) == codes.NotFound {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, s3.ErrCodeNoSuchBucket) || strings.Contains(msg, iam.ErrCodeNoSuchEntityException)
+}
+
 func (ctrl *Controller) createBucket(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, pbclass *crdv1alpha1.PXBucketClass) error {
-	bucketID := getBucketID(pbc)
+	bucketID, err := getBucketID(pbc, pbclass)
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid bucket name: %v", err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonInvalidBucketName, errMsg)
+		return err
+	}
+	anonymousAccessMode, err := getAnonymousAccessMode(pbc, pbclass)
+	if err == nil {
+		err = checkAnonymousAccessSupported(pbclass.Parameters[backendTypeKey], anonymousAccessMode)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid anonymous access mode: %v", err)
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", errMsg)
+		ctrl.recordBucketClaimError(ctx, pbc, reasonAnonymousAccessFailed, errMsg)
+		return err
+	}
+	// Templated names may repeat across claims. Never hand out a bucket
+	// that already belongs to another claim.
+	if err := ctrl.checkBucketIDUnused(pbc, bucketID); err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", err.Error())
+		ctrl.recordBucketClaimError(ctx, pbc, reasonInvalidBucketName, err.Error())
+		return err
+	}
+	// Nor a bucket that exists but is not tracked by the controller
+	if isNewTemplatedBucketID(pbc, pbclass) {
+		if err := ctrl.checkBucketNotExists(ctx, pbclass, bucketID); err != nil {
+			ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", err.Error())
+			ctrl.recordBucketClaimError(ctx, pbc, reasonInvalidBucketName, err.Error())
+			return err
+		}
+	}
 
-	_, err := ctrl.bucketClient.CreateBucket(ctx, &api.BucketCreateRequest{
-		Name:     bucketID,
-		Region:   pbclass.Region,
-		Endpoint: pbclass.Parameters[endpointKey],
+	// Add the finalizer before the backend call so that a bucket is never
+	// created without the controller being able to clean it up.
+	patched, err := ctrl.patchBucketClaimMetadata(ctx, pbc, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = addFinalizer(meta.Finalizers, bucketProvisionedFinalizer)
+		meta.Finalizers = addFinalizer(meta.Finalizers, bucketClaimProtectionFinalizer)
 	})
 	if err != nil {
-		logrus.WithContext(ctx).Infof("create bucket %s failed: %v", pbc.Name, err)
-		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to create bucket: %v", err))
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to add finalizer: %v", err))
 		return err
 	}
+	pbc = patched
 
-	logrus.WithContext(ctx).Infof("bucket %q created", pbc.Name)
-	if pbc.Status == nil {
-		pbc.Status = &crdv1alpha1.BucketClaimStatus{}
-	}
-	pbc.Status.Provisioned = true
+	// Persist everything needed to delete the bucket later on
+	bucketClaimStatusFields(pbc).setPhase(crdv1alpha1.BucketPhaseProvisioning, reasonProvisioning, fmt.Sprintf("provisioning bucket %s", bucketID))
 	pbc.Status.Region = pbclass.Region
 	pbc.Status.DeletionPolicy = pbclass.DeletionPolicy
 	pbc.Status.BucketID = bucketID
+	pbc.Status.BucketName = bucketObjectName(bucketID)
 	pbc.Status.BackendType = pbclass.Parameters[backendTypeKey]
 	pbc.Status.Endpoint = pbclass.Parameters[endpointKey]
-	pbc.Finalizers = append(pbc.Finalizers, bucketProvisionedFinalizer)
-	if pbc.Annotations == nil {
-		pbc.Annotations = make(map[string]string)
+	pbc.Status.ClearBucket = parseClearBucket(pbclass.Parameters[clearBucketKey])
+	pbc.Status.AnonymousAccessMode = anonymousAccessMode
+	updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+	if err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to update bucket status: %v", err))
+		return err
 	}
-	if clearBucketVal, ok := pbclass.Parameters[clearBucketKey]; ok {
-		pbc.Annotations[clearBucketKey] = clearBucketVal
+	pbc = updated
+
+	_, err = ctrl.bucketClient.CreateBucket(ctx, &api.BucketCreateRequest{
+		Name:                      bucketID,
+		Region:                    pbclass.Region,
+		Endpoint:                  pbclass.Parameters[endpointKey],
+		AnonymousBucketAccessMode: anonymousAccessModes[anonymousAccessMode],
+	})
+	if err != nil {
+		logrus.WithContext(ctx).Infof("create bucket %s failed: %v", pbc.Name, err)
+		// The bucket may belong to someone else. The name is rendered and
+		// checked again on the next attempt.
+		pbc.Status.BucketID = ""
+		pbc.Status.BucketName = ""
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to create bucket: %v", err))
+		ctrl.recordBucketClaimError(ctx, pbc, reasonCreateBucketFailed, fmt.Sprintf("failed to create bucket: %v", err))
+		return err
 	}
-	pbc, err = ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Update(ctx, pbc, metav1.UpdateOptions{})
+
+	logrus.WithContext(ctx).Infof("bucket %q created", pbc.Name)
+	// Record the created bucket right away, the bucket is only ever deleted
+	// with a provisioned claim
+	bucketClaimStatusFields(pbc).setPhase(crdv1alpha1.BucketPhaseReady, reasonBucketProvisioned, fmt.Sprintf("bucket %s is provisioned", bucketID))
+	pbc.Status.Provisioned = true
+	updated, err = ctrl.updateBucketClaimStatus(ctx, pbc)
 	if err != nil {
-		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to update bucket: %v", err))
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to update bucket status: %v", err))
+		return err
+	}
+	pbc = updated
+	ctrl.tagBucket(ctx, pbc.Status.BackendType, bucketID, pbc.Status.Region, pbc.Status.Endpoint)
+
+	// The claim is provisioned at this point. If recording the PXBucket fails
+	// it is retried from the already provisioned path.
+	if err := ctrl.createBucketObject(ctx, pbc, pbclass); err != nil {
+		ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "CreateBucketError", fmt.Sprintf("failed to create bucket object: %v", err))
 		return err
 	}
 
@@ -147,16 +262,8 @@ func (ctrl *Controller) setupContextFromValue(ctx context.Context, backendType s
 }
 
 func (ctrl *Controller) setupContextFromClass(ctx context.Context, pbclass *crdv1alpha1.PXBucketClass) (context.Context, error) {
-	backendTypeValue, ok := pbclass.Parameters[backendTypeKey]
-	if !ok {
-		err := fmt.Errorf("PXBucketClass parameter %s is unset", backendTypeKey)
-		logrus.WithContext(ctx).Error(err)
-
-		return ctx, err
-	}
-
-	if _, ok = allowedDrivers[backendTypeValue]; !ok {
-		err := fmt.Errorf("PXBucketClass parameter %s is invalid. Possible values are: %v", backendTypeKey, allowedDrivers)
+	backendTypeValue := pbclass.Parameters[backendTypeKey]
+	if err := validateBackendType(backendTypeValue); err != nil {
 		logrus.WithContext(ctx).Error(err)
 
 		return ctx, err
@@ -174,81 +281,116 @@ func getCredentialsSecretName(pba *crdv1alpha1.PXBucketAccess) string {
 	if pba.Status != nil && pba.Status.CredentialsSecretName != "" {
 		return pba.Status.CredentialsSecretName
 	}
+	if pba.Spec.CredentialsSecretName != "" {
+		return pba.Spec.CredentialsSecretName
+	}
+	return getDefaultCredentialsSecretName(pba)
+}
+
+func getDefaultCredentialsSecretName(pba *crdv1alpha1.PXBucketAccess) string {
 	return fmt.Sprintf("px-os-credentials-%s", pba.Name)
 }
 
-func (ctrl *Controller) createAccess(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, bucketID string) error {
+func (ctrl *Controller) createAccess(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, pbaclass *crdv1alpha1.PXBucketAccessClass, bucketID, accessPolicy string) error {
+	// Add the finalizer before the backend call so that access is never
+	// granted without the controller being able to revoke it.
+	patched, err := ctrl.patchBucketAccessMetadata(ctx, pba, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = addFinalizer(meta.Finalizers, accessGrantedFinalizer)
+	})
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to add finalizer to bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
+		return err
+	}
+	pba = patched
+
+	// Persist the bucket before the backend call so that an interrupted
+	// grant can be revoked
+	if pba.Status == nil || pba.Status.Phase != crdv1alpha1.BucketPhaseProvisioning || pba.Status.BucketId != bucketID {
+		bucketAccessStatusFields(pba)
+		pba.Status.BucketId = bucketID
+		pba.Status.BackendType = pbclass.Parameters[backendTypeKey]
+		pba, err = ctrl.setBucketAccessPhase(ctx, pba, crdv1alpha1.BucketPhaseProvisioning, reasonProvisioning, fmt.Sprintf("granting access to bucket %s", bucketID))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Get namespace UID for multitenancy
 	namespace, err := ctrl.k8sClient.CoreV1().Namespaces().Get(ctx, pba.Namespace, metav1.GetOptions{})
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to get namespace during grant bucket access %s: %v", pba.Name, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonGrantAccessFailed, errMsg)
 		return err
 	}
 
 	resp, err := ctrl.bucketClient.AccessBucket(ctx, &api.BucketGrantAccessRequest{
-		BucketId:    bucketID,
-		AccountName: getAccountName(namespace),
+		BucketId:     bucketID,
+		AccountName:  getAccountName(namespace),
+		AccessPolicy: accessPolicy,
 	})
 	if err != nil {
 		errMsg := fmt.Sprintf("create bucket access %s failed: %v", pba.Name, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonGrantAccessFailed, errMsg)
 		return err
 	}
 
-	accessData := make(map[string]string)
-	accessData["access-key-id"] = resp.Credentials.GetAccessKeyId()
-	accessData["secret-access-key"] = resp.Credentials.GetSecretAccessKey()
-	accessData["endpoint"] = pbclass.Parameters[endpointKey]
-	accessData["region"] = pbclass.Region
-	accessData["bucket-id"] = bucketID
+	pba.Status.AccountId = resp.GetAccountId()
+	ctrl.tagAccount(ctx, pba.Status.BackendType, pba.Status.AccountId)
+	// Persist the account right away so that the grant can be revoked even
+	// if the access is deleted before it is marked as granted
+	updated, err := ctrl.updateBucketAccessStatus(ctx, pba)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to update bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
+		return err
+	}
+	pba = updated
+	accessKeyID, secretAccessKey, keyCreationTime, err := ctrl.checkGrantedAccessKey(ctx, pba, resp.Credentials.GetAccessKeyId(), resp.Credentials.GetSecretAccessKey())
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to check access key for bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonGrantAccessFailed, errMsg)
+		return err
+	}
 
-	// If secret exists, update it.
-	credentialsSecretName := getCredentialsSecretName(pba)
-	secret, err := ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Get(ctx, credentialsSecretName, metav1.GetOptions{})
-	if k8s_errors.IsNotFound(err) {
-		// Create if it doesn't exist
-		secret, err = ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Create(
-			ctx,
-			&corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:       credentialsSecretName,
-					Namespace:  pba.Namespace,
-					Finalizers: []string{accessSecretFinalizer},
-				},
-				StringData: accessData,
-			},
-			metav1.CreateOptions{},
-		)
-		if err != nil {
-			errMsg := fmt.Sprintf("failed to create access secret for bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
-			ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
-			return err
+	err = ctrl.writeSourceCredentials(ctx, pba, accessKeyID, secretAccessKey)
+	if err == nil {
+		var accessData map[string]string
+		accessData, err = renderCredentials(getCredentialsFormat(pba, pbaclass), pbaclass, getCredentialsParams(pba, pbclass, bucketID, accessKeyID, secretAccessKey))
+		if err == nil {
+			_, err = ctrl.applySecretData(ctx, pba, getCredentialsSecretName(pba), credentialsKindRendered, accessData)
 		}
-	} else if err != nil {
-		errMsg := fmt.Sprintf("failed to get secret for bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to create access secret for bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonGrantAccessFailed, errMsg)
 		return err
 	}
 
 	logrus.WithContext(ctx).Infof("bucket access %q created", pba.Name)
-	if pba.Status == nil {
-		pba.Status = &crdv1alpha1.BucketAccessStatus{}
-	}
+	bucketAccessStatusFields(pba).setPhase(crdv1alpha1.BucketPhaseReady, reasonAccessGranted, fmt.Sprintf("access granted to bucket %s", bucketID))
 	pba.Status.AccessGranted = true
-	pba.Status.CredentialsSecretName = secret.Name
-	pba.Status.AccountId = resp.GetAccountId()
+	pba.Status.CredentialsSecretName = getCredentialsSecretName(pba)
 	pba.Status.BucketId = bucketID
-	pba.Status.BackendType = pbclass.Parameters[backendTypeKey]
-	pba.Finalizers = []string{accessGrantedFinalizer}
-	pba, err = ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace).Update(ctx, pba, metav1.UpdateOptions{})
+	pba.Status.AccessMode = getAccessMode(pba)
+	pba.Status.CredentialsFormat = getCredentialsFormat(pba, pbaclass)
+	if keyCreationTime != nil {
+		pba.Status.AccessKeyId = accessKeyID
+		pba.Status.KeyCreationTime = keyCreationTime
+	}
+	updated, err = ctrl.updateBucketAccessStatus(ctx, pba)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to update bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "GrantAccessError", errMsg)
 		return err
 	}
+	pba = updated
 
 	_, err = ctrl.storeAccessUpdate(pba)
 	if err != nil {
@@ -262,13 +404,35 @@ func (ctrl *Controller) createAccess(ctx context.Context, pba *crdv1alpha1.PXBuc
 func (ctrl *Controller) revokeAccess(ctx context.Context, pba *crdv1alpha1.PXBucketAccess) error {
 
 	if pba.Status == nil || !pba.Status.AccessGranted {
-		logrus.WithContext(ctx).Infof("bucket not yet provisioned. skipping backened delete")
+		// A grant may have been interrupted after the backend call but
+		// before the access was marked as granted
+		if pba.Status != nil && pba.Status.BucketId != "" && pba.Status.AccountId != "" {
+			logrus.WithContext(ctx).Infof("access of account %s to bucket %s not marked as granted, revoking it in case it was granted", pba.Status.AccountId, pba.Status.BucketId)
+			_, err := ctrl.bucketClient.RevokeBucket(ctx, &api.BucketRevokeAccessRequest{
+				BucketId:  pba.Status.BucketId,
+				AccountId: pba.Status.AccountId,
+			})
+			if err != nil && !isBackendNotFound(err) {
+				errMsg := fmt.Sprintf("revoke bucket %s failed: %v", pba.Name, err)
+				logrus.WithContext(ctx).Errorf(errMsg)
+				ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RevokeAccessError", errMsg)
+				ctrl.recordBucketAccessError(ctx, pba, reasonRevokeAccessFailed, errMsg)
+				return err
+			}
+		} else {
+			logrus.WithContext(ctx).Infof("bucket not yet provisioned. skipping backened delete")
+		}
 		err := ctrl.removeAccessFinalizers(ctx, pba)
 		if err != nil {
 			return err
 		}
 
-		return ctrl.accessStore.Delete(pba)
+		ctrl.storeAccessDelete(pba)
+		return nil
+	}
+
+	if pba.Status.Phase != crdv1alpha1.BucketPhaseDeleting {
+		pba, _ = ctrl.setBucketAccessPhase(ctx, pba, crdv1alpha1.BucketPhaseDeleting, reasonDeleting, "bucket access is being revoked")
 	}
 
 	// Provisioned and deletionPolicy is delte. Delete the bucket here.
@@ -280,14 +444,28 @@ func (ctrl *Controller) revokeAccess(ctx context.Context, pba *crdv1alpha1.PXBuc
 		errMsg := fmt.Sprintf("revoke bucket %s failed: %v", pba.Name, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RevokeAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonRevokeAccessFailed, errMsg)
 		return err
 	}
 
-	err = ctrl.removeSecretFinalizersAndDelete(ctx, pba.Status.CredentialsSecretName, pba.Namespace)
+	// Keys replaced by a rotation are not known to the driver
+	if pba.Status.PreviousAccessKeyId != "" {
+		if backendClient, err := ctrl.getRotationBackend(pba.Status.BackendType); err == nil {
+			if err := ctrl.revokePreviousAccessKey(ctx, pba, backendClient, true); err != nil {
+				logrus.WithContext(ctx).Errorf("failed to revoke previous access key of bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
+			}
+		}
+	}
+
+	err = ctrl.removeSecretFinalizersAndDelete(ctx, pba, pba.Status.CredentialsSecretName)
+	if err == nil {
+		err = ctrl.removeSecretFinalizersAndDelete(ctx, pba, getCredentialsSourceName(pba))
+	}
 	if err != nil {
 		errMsg := fmt.Sprintf("bucket access secret %s delete failed: %v", pba.Status.CredentialsSecretName, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RevokeAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonRevokeAccessFailed, errMsg)
 		return err
 	}
 
@@ -296,53 +474,91 @@ func (ctrl *Controller) revokeAccess(ctx context.Context, pba *crdv1alpha1.PXBuc
 		errMsg := fmt.Sprintf("bucket access %s/%s remove finalizer failed: %v", pba.Namespace, pba.Name, err)
 		logrus.WithContext(ctx).Errorf(errMsg)
 		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RevokeAccessError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonRevokeAccessFailed, errMsg)
 		return err
 	}
 
-	ctrl.accessStore.Delete(pba)
+	ctrl.storeAccessDelete(pba)
 	logrus.WithContext(ctx).Infof("bucket access %q deleted", pba.Name)
 
 	return nil
 }
 
+// storeBucketUpdate records the bucket in the optional in-memory bucket store
 func (ctrl *Controller) storeBucketUpdate(bucket interface{}) (bool, error) {
+	if ctrl.bucketStore == nil {
+		return false, nil
+	}
 	return utils.StoreObjectUpdate(ctrl.bucketStore, bucket, "bucket")
 }
 
+// storeBucketDelete removes the bucket from the optional in-memory bucket store
+func (ctrl *Controller) storeBucketDelete(bucket interface{}) {
+	if ctrl.bucketStore == nil {
+		return
+	}
+	if err := ctrl.bucketStore.Delete(bucket); err != nil {
+		logrus.Errorf("failed to delete bucket from cache: %v", err)
+	}
+}
+
+// storeAccessUpdate records the access in the optional in-memory access store
 func (ctrl *Controller) storeAccessUpdate(access interface{}) (bool, error) {
+	if ctrl.accessStore == nil {
+		return false, nil
+	}
 	return utils.StoreObjectUpdate(ctrl.accessStore, access, "access")
 }
 
-func (ctrl *Controller) removeBucketFinalizers(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) error {
-	pbc, err := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(ctx, pbc.Name, metav1.GetOptions{})
-	if err != nil {
-		return err
+// storeAccessDelete removes the access from the optional in-memory access store
+func (ctrl *Controller) storeAccessDelete(access interface{}) {
+	if ctrl.accessStore == nil {
+		return
+	}
+	if err := ctrl.accessStore.Delete(access); err != nil {
+		logrus.Errorf("failed to delete bucket access from cache: %v", err)
 	}
+}
 
-	pbc.Finalizers = []string{}
-	_, err = ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Update(ctx, pbc, metav1.UpdateOptions{})
+// removeBucketFinalizers removes the finalizers owned by the controller from the
+// PXBucketClaim. Finalizers added by anything else are left in place.
+func (ctrl *Controller) removeBucketFinalizers(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) error {
+	_, err := ctrl.patchBucketClaimMetadata(ctx, pbc, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = removeFinalizer(meta.Finalizers, bucketProvisionedFinalizer)
+		meta.Finalizers = removeFinalizer(meta.Finalizers, bucketClaimProtectionFinalizer)
+	})
 	return err
 }
 
+// removeAccessFinalizers removes the finalizers owned by the controller from the
+// PXBucketAccess. Finalizers added by anything else are left in place.
 func (ctrl *Controller) removeAccessFinalizers(ctx context.Context, pba *crdv1alpha1.PXBucketAccess) error {
-	pba, err := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace).Get(ctx, pba.Name, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-
-	pba.Finalizers = []string{}
-	_, err = ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace).Update(ctx, pba, metav1.UpdateOptions{})
+	_, err := ctrl.patchBucketAccessMetadata(ctx, pba, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = removeFinalizer(meta.Finalizers, accessGrantedFinalizer)
+	})
 	return err
 }
 
-func (ctrl *Controller) removeSecretFinalizersAndDelete(ctx context.Context, secretName, secretNamespace string) error {
+// removeSecretFinalizersAndDelete removes the finalizer owned by the controller
+// from the access secret and deletes it. Secrets that are not owned by the
+// PXBucketAccess are left in place.
+func (ctrl *Controller) removeSecretFinalizersAndDelete(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, secretName string) error {
+	secretNamespace := pba.Namespace
 	secret, err := ctrl.k8sClient.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
-	if err != nil {
+	if k8s_errors.IsNotFound(err) {
+		logrus.WithContext(ctx).Infof("bucket access secret %s/%s already deleted", secretNamespace, secretName)
+		return nil
+	} else if err != nil {
 		return err
 	}
+	if !isSecretOwnedBy(secret, pba) {
+		logrus.WithContext(ctx).Infof("bucket access secret %s/%s is not owned by bucket access %s, skipping delete", secretNamespace, secretName, pba.Name)
+		return nil
+	}
 
-	secret.Finalizers = []string{}
-	secret, err = ctrl.k8sClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	secret, err = ctrl.patchSecretMetadata(ctx, secret, func(meta *metav1.ObjectMeta) {
+		meta.Finalizers = removeFinalizer(meta.Finalizers, accessSecretFinalizer)
+	})
 	if err != nil {
 		return err
 	}
@@ -357,6 +573,13 @@ func (ctrl *Controller) removeSecretFinalizersAndDelete(ctx context.Context, sec
 	return nil
 }
 
-func getBucketID(pbc *v1alpha1.PXBucketClaim) string {
-	return fmt.Sprintf("px-os-%s", pbc.GetUID())
+func parseClearBucket(clearBucketVal string) bool {
+	if clearBucketVal == "" {
+		return false
+	}
+	clearBucket, err := strconv.ParseBool(clearBucketVal)
+	if err != nil {
+		logrus.Errorf("invalid value %s for %s, defaulting to false: %v", clearBucketVal, clearBucketKey, err)
+	}
+	return clearBucket
 }
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsBackendNotFound(t *testing.T) {
+	notFound := []error{
+		status.Error(codes.NotFound, "bucket not found"),
+		status.Error(codes.Internal, "Failed to delete bucket: NoSuchBucket: The specified bucket does not exist"),
+		status.Error(codes.Internal, "Failed to revoke access: NoSuchEntity: The user with name px-os-access cannot be found"),
+	}
+	for _, err := range notFound {
+		if !isBackendNotFound(err) {
+			t.Errorf("expected %v to be a not found error", err)
+		}
+	}
+
+	for _, err := range []error{
+		status.Error(codes.Internal, "Failed to delete bucket: AccessDenied"),
+		errors.New("connection refused"),
+	} {
+		if isBackendNotFound(err) {
+			t.Errorf("expected %v not to be a not found error", err)
+		}
+	}
+}
+
+func TestDeleteUnprovisionedBucketKeepsBackendBucket(t *testing.T) {
+	pbc := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "claim",
+			Namespace:  "tenant-a",
+			Finalizers: []string{bucketProvisionedFinalizer, bucketClaimProtectionFinalizer},
+		},
+		Status: &crdv1alpha1.BucketClaimStatus{
+			Phase:          crdv1alpha1.BucketPhaseProvisioning,
+			BucketID:       "team-a-images",
+			DeletionPolicy: crdv1alpha1.PXBucketClaimDelete,
+		},
+	}
+	client := fake.NewSimpleClientset(pbc)
+	// The SDK client is not set, any backend call fails the test
+	ctrl := &Controller{
+		config:          &Config{},
+		k8sBucketClient: client,
+		eventRecorder:   record.NewFakeRecorder(10),
+		bucketStore:     cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc),
+	}
+
+	if err := ctrl.deleteBucket(context.Background(), pbc.DeepCopy()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := client.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.Background(), pbc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get bucket claim: %v", err)
+	}
+	if len(updated.Finalizers) != 0 {
+		t.Errorf("expected finalizers to be removed, got %v", updated.Finalizers)
+	}
+}
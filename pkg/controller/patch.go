@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// hasFinalizer returns true if finalizer is in finalizers.
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// addFinalizer appends finalizer to finalizers if it is not already present.
+func addFinalizer(finalizers []string, finalizer string) []string {
+	if hasFinalizer(finalizers, finalizer) {
+		return finalizers
+	}
+	return append(finalizers, finalizer)
+}
+
+// removeFinalizer returns finalizers without finalizer, keeping the order
+// of all other finalizers.
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	result := make([]string, 0, len(finalizers))
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// metadataMutation changes the finalizers and annotations of an object.
+type metadataMutation func(meta *metav1.ObjectMeta)
+
+// metadataMergePatch applies mutate to a copy of the finalizers and annotations
+// of meta and returns a JSON merge patch with the difference. The patch carries
+// the resourceVersion of meta so that it fails with a conflict if the object
+// changed in the meantime. If mutate changes nothing, a nil patch is returned.
+func metadataMergePatch(meta metav1.ObjectMeta, mutate metadataMutation) ([]byte, error) {
+	mutated := metav1.ObjectMeta{
+		Finalizers:  append([]string{}, meta.Finalizers...),
+		Annotations: make(map[string]string, len(meta.Annotations)),
+	}
+	for k, v := range meta.Annotations {
+		mutated.Annotations[k] = v
+	}
+	mutate(&mutated)
+
+	patchMeta := map[string]interface{}{
+		"resourceVersion": meta.ResourceVersion,
+	}
+	if !reflect.DeepEqual(append([]string{}, meta.Finalizers...), append([]string{}, mutated.Finalizers...)) {
+		patchMeta["finalizers"] = append([]string{}, mutated.Finalizers...)
+	}
+	annotations := make(map[string]interface{})
+	for k, v := range mutated.Annotations {
+		if old, ok := meta.Annotations[k]; !ok || old != v {
+			annotations[k] = v
+		}
+	}
+	for k := range meta.Annotations {
+		if _, ok := mutated.Annotations[k]; !ok {
+			annotations[k] = nil
+		}
+	}
+	if len(annotations) > 0 {
+		patchMeta["annotations"] = annotations
+	}
+	if len(patchMeta) == 1 {
+		return nil, nil
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"metadata": patchMeta,
+	})
+}
+
+// metadataPatchFunc sends a merge patch for the named object and returns the
+// patched object.
+type metadataPatchFunc func(name string, patch []byte) (metav1.Object, error)
+
+// metadataGetFunc returns the latest version of the named object.
+type metadataGetFunc func(name string) (metav1.Object, error)
+
+// patchMetadata applies mutate to the finalizers and annotations of obj with a
+// merge patch sent by patch. On conflict the latest object is fetched with get
+// and mutate is applied again. The returned object has the same type as obj.
+func patchMetadata(obj metav1.Object, mutate metadataMutation, patch metadataPatchFunc, get metadataGetFunc) (metav1.Object, error) {
+	current := obj
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		data, err := metadataMergePatch(metav1.ObjectMeta{
+			ResourceVersion: current.GetResourceVersion(),
+			Finalizers:      current.GetFinalizers(),
+			Annotations:     current.GetAnnotations(),
+		}, mutate)
+		if err != nil || data == nil {
+			return err
+		}
+
+		updated, err := patch(current.GetName(), data)
+		if k8s_errors.IsConflict(err) {
+			latest, getErr := get(current.GetName())
+			if getErr != nil {
+				return getErr
+			}
+			current = latest
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		current = updated
+		return nil
+	})
+	return current, err
+}
+
+// patchBucketClaimMetadata applies mutate to the finalizers and annotations of the
+// PXBucketClaim with a merge patch.
+func (ctrl *Controller) patchBucketClaimMetadata(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, mutate metadataMutation) (*crdv1alpha1.PXBucketClaim, error) {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace)
+	patched, err := patchMetadata(pbc, mutate,
+		func(name string, patch []byte) (metav1.Object, error) {
+			return client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		},
+		func(name string) (metav1.Object, error) {
+			return client.Get(ctx, name, metav1.GetOptions{})
+		},
+	)
+	return patched.(*crdv1alpha1.PXBucketClaim), err
+}
+
+// patchBucketAccessMetadata applies mutate to the finalizers and annotations of the
+// PXBucketAccess with a merge patch.
+func (ctrl *Controller) patchBucketAccessMetadata(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, mutate metadataMutation) (*crdv1alpha1.PXBucketAccess, error) {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace)
+	patched, err := patchMetadata(pba, mutate,
+		func(name string, patch []byte) (metav1.Object, error) {
+			return client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		},
+		func(name string) (metav1.Object, error) {
+			return client.Get(ctx, name, metav1.GetOptions{})
+		},
+	)
+	return patched.(*crdv1alpha1.PXBucketAccess), err
+}
+
+// patchSecretMetadata applies mutate to the finalizers and annotations of the
+// Secret with a merge patch.
+func (ctrl *Controller) patchSecretMetadata(ctx context.Context, secret *corev1.Secret, mutate metadataMutation) (*corev1.Secret, error) {
+	client := ctrl.k8sClient.CoreV1().Secrets(secret.Namespace)
+	patched, err := patchMetadata(secret, mutate,
+		func(name string, patch []byte) (metav1.Object, error) {
+			return client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		},
+		func(name string) (metav1.Object, error) {
+			return client.Get(ctx, name, metav1.GetOptions{})
+		},
+	)
+	return patched.(*corev1.Secret), err
+}
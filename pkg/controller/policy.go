@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+)
+
+// accessModeActions are the S3 actions allowed by each access mode
+var accessModeActions = map[crdv1alpha1.BucketAccessMode][]string{
+	crdv1alpha1.BucketAccessReadOnly: {
+		"s3:GetObject",
+		"s3:GetObjectVersion",
+		"s3:GetBucketLocation",
+		"s3:ListBucket",
+	},
+	crdv1alpha1.BucketAccessWriteOnly: {
+		"s3:PutObject",
+		"s3:AbortMultipartUpload",
+		"s3:ListMultipartUploadParts",
+	},
+	crdv1alpha1.BucketAccessReadWrite: {
+		"s3:*",
+	},
+}
+
+// accessPolicyDrivers are the backend types that apply the access policy of a grant.
+// Other drivers grant full access regardless of the policy.
+var accessPolicyDrivers = map[string]bool{
+	"S3Driver": true,
+}
+
+// accessPolicyParams are the values available to a policy template
+type accessPolicyParams struct {
+	// BucketID is the ID of the bucket
+	BucketID string
+	// Namespace is the namespace of the PXBucketAccess
+	Namespace string
+	// Name is the name of the PXBucketAccess
+	Name string
+	// AccessMode is the access mode of the PXBucketAccess
+	AccessMode crdv1alpha1.BucketAccessMode
+	// Actions are the S3 actions allowed by the access mode
+	Actions []string
+}
+
+var policyTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+}
+
+// getAccessMode returns the access mode of the PXBucketAccess
+func getAccessMode(pba *crdv1alpha1.PXBucketAccess) crdv1alpha1.BucketAccessMode {
+	if pba.Spec.AccessMode == "" {
+		return crdv1alpha1.BucketAccessReadWrite
+	}
+	return pba.Spec.AccessMode
+}
+
+// renderAccessPolicy returns the IAM policy document for the PXBucketAccess.
+// An empty policy means the default full access of the driver.
+func renderAccessPolicy(pba *crdv1alpha1.PXBucketAccess, pbaclass *crdv1alpha1.PXBucketAccessClass, bucketID string) (string, error) {
+	accessMode := getAccessMode(pba)
+	actions, ok := accessModeActions[accessMode]
+	if !ok {
+		return "", fmt.Errorf("invalid access mode %q", accessMode)
+	}
+	params := &accessPolicyParams{
+		BucketID:   bucketID,
+		Namespace:  pba.Namespace,
+		Name:       pba.Name,
+		AccessMode: accessMode,
+		Actions:    actions,
+	}
+
+	if pbaclass != nil && pbaclass.PolicyTemplate != "" {
+		return renderPolicyTemplate(pbaclass.PolicyTemplate, params)
+	}
+	if accessMode == crdv1alpha1.BucketAccessReadWrite {
+		return "", nil
+	}
+	return defaultAccessPolicy(params)
+}
+
+// defaultAccessPolicy allows the actions of the access mode on the bucket and its objects
+func defaultAccessPolicy(params *accessPolicyParams) (string, error) {
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect": "Allow",
+				"Action": params.Actions,
+				"Resource": []string{
+					fmt.Sprintf("arn:aws:s3:::%s", params.BucketID),
+					fmt.Sprintf("arn:aws:s3:::%s/*", params.BucketID),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
+
+// renderPolicyTemplate renders a policy template and checks that the result is valid JSON
+func renderPolicyTemplate(policyTemplate string, params *accessPolicyParams) (string, error) {
+	tmpl, err := template.New("policy").Option("missingkey=error").Funcs(policyTemplateFuncs).Parse(policyTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse policy template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", fmt.Errorf("failed to render policy template: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return "", fmt.Errorf("policy template does not render valid JSON: %s", buf.String())
+	}
+	return buf.String(), nil
+}
+
+// validatePolicyTemplate renders the template with sample values
+func validatePolicyTemplate(policyTemplate string) error {
+	_, err := renderPolicyTemplate(policyTemplate, &accessPolicyParams{
+		BucketID:   "bucket",
+		Namespace:  "namespace",
+		Name:       "name",
+		AccessMode: crdv1alpha1.BucketAccessReadOnly,
+		Actions:    accessModeActions[crdv1alpha1.BucketAccessReadOnly],
+	})
+	return err
+}
+
+// checkAccessPolicySupported returns an error if the backend cannot apply the policy
+func checkAccessPolicySupported(backendType, policy string) error {
+	if policy == "" || accessPolicyDrivers[backendType] {
+		return nil
+	}
+	return fmt.Errorf("backend %s does not support access policies. Only ReadWrite access without a PXBucketAccessClass policy template is supported", backendType)
+}
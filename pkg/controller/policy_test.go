@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderAccessPolicy(t *testing.T) {
+	newAccess := func(mode crdv1alpha1.BucketAccessMode) *crdv1alpha1.PXBucketAccess {
+		return &crdv1alpha1.PXBucketAccess{
+			ObjectMeta: metav1.ObjectMeta{Name: "analytics", Namespace: "team-a"},
+			Spec:       crdv1alpha1.BucketAccessSpec{AccessMode: mode},
+		}
+	}
+
+	// ReadWrite without a template keeps the driver default
+	policy, err := renderAccessPolicy(newAccess(""), nil, "bucket")
+	if err != nil || policy != "" {
+		t.Fatalf("expected default policy, got %q: %v", policy, err)
+	}
+
+	policy, err = renderAccessPolicy(newAccess(crdv1alpha1.BucketAccessReadOnly), nil, "bucket")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc struct {
+		Statement []struct {
+			Action   []string
+			Resource []string
+		}
+	}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		t.Fatalf("policy is not valid JSON: %v", err)
+	}
+	if len(doc.Statement) != 1 || !reflect.DeepEqual(doc.Statement[0].Action, accessModeActions[crdv1alpha1.BucketAccessReadOnly]) {
+		t.Fatalf("unexpected read only policy: %s", policy)
+	}
+	if !reflect.DeepEqual(doc.Statement[0].Resource, []string{"arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"}) {
+		t.Fatalf("unexpected policy resources: %s", policy)
+	}
+
+	pbaclass := &crdv1alpha1.PXBucketAccessClass{
+		PolicyTemplate: `{"Statement":[{"Effect":"Allow","Action":{{json .Actions}},"Resource":["arn:aws:s3:::{{.BucketID}}/{{.Namespace}}/*"]}]}`,
+	}
+	policy, err = renderAccessPolicy(newAccess(crdv1alpha1.BucketAccessWriteOnly), pbaclass, "bucket")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"Statement":[{"Effect":"Allow","Action":["s3:PutObject","s3:AbortMultipartUpload","s3:ListMultipartUploadParts"],"Resource":["arn:aws:s3:::bucket/team-a/*"]}]}`
+	if policy != expected {
+		t.Fatalf("expected policy %s, got %s", expected, policy)
+	}
+
+	pbaclass.PolicyTemplate = `{"Statement": {{.Actions}}}`
+	if _, err := renderAccessPolicy(newAccess(crdv1alpha1.BucketAccessReadOnly), pbaclass, "bucket"); err == nil {
+		t.Fatalf("expected error for policy template rendering invalid JSON")
+	}
+}
+
+func TestCheckAccessPolicySupported(t *testing.T) {
+	if err := checkAccessPolicySupported("PureFBDriver", ""); err != nil {
+		t.Fatalf("expected default policy to be supported: %v", err)
+	}
+	if err := checkAccessPolicySupported("PureFBDriver", "{}"); err == nil {
+		t.Fatalf("expected custom policy to be rejected")
+	}
+	if err := checkAccessPolicySupported("S3Driver", "{}"); err != nil {
+		t.Fatalf("expected custom policy to be supported: %v", err)
+	}
+}
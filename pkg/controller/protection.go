@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// bucketClaimProtectionFinalizer holds PXBucketClaim deletion until
+	// no PXBucketAccess references the claim anymore.
+	bucketClaimProtectionFinalizer = commonObjectServiceFinalizerKeyPrefix + "bucket-claim-protection"
+
+	// cascadeDeleteKey is a PXBucketClaim annotation. When set to true, deleting
+	// the claim also deletes all PXBucketAccesses that reference it.
+	cascadeDeleteKey = commonObjectServiceKeyPrefix + "cascade-delete"
+)
+
+// getBucketClaimAccesses returns the PXBucketAccesses referencing the PXBucketClaim
+func (ctrl *Controller) getBucketClaimAccesses(pbc *crdv1alpha1.PXBucketClaim) ([]*crdv1alpha1.PXBucketAccess, error) {
+	objs, err := ctrl.accessIndexer.ByIndex(bucketClaimIndex, pbc.Namespace+"/"+pbc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	accesses := make([]*crdv1alpha1.PXBucketAccess, 0, len(objs))
+	for _, obj := range objs {
+		if access, ok := obj.(*crdv1alpha1.PXBucketAccess); ok {
+			accesses = append(accesses, access)
+		}
+	}
+	return accesses, nil
+}
+
+// checkBucketClaimInUse returns true if PXBucketAccesses still reference the
+// PXBucketClaim. In cascade mode the referencing accesses are deleted,
+// otherwise the claim is marked as in use. Either way the claim is requeued
+// once the last access is gone.
+func (ctrl *Controller) checkBucketClaimInUse(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) (bool, error) {
+	if !hasFinalizer(pbc.Finalizers, bucketClaimProtectionFinalizer) {
+		return false, nil
+	}
+
+	accesses, err := ctrl.getBucketClaimAccesses(pbc)
+	if err != nil {
+		return false, err
+	}
+	if len(accesses) == 0 {
+		return false, nil
+	}
+
+	if cascadeDelete(pbc) {
+		for _, access := range accesses {
+			if access.DeletionTimestamp != nil {
+				continue
+			}
+			logrus.WithContext(ctx).Infof("deleting bucket access %s/%s of deleted bucket claim %s", access.Namespace, access.Name, pbc.Name)
+			err := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(access.Namespace).Delete(ctx, access.Name, metav1.DeleteOptions{})
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	// Only record changes so that repeated syncs do not cause event storms
+	msg := fmt.Sprintf("bucket claim is in use by %d bucket access(es) and will be deleted once they are removed", len(accesses))
+	if pbc.Status != nil {
+		cond := meta.FindStatusCondition(pbc.Status.Conditions, crdv1alpha1.ConditionDeleting)
+		if cond != nil && cond.Reason == reasonBucketClaimInUse && cond.Message == msg {
+			return true, nil
+		}
+	}
+	ctrl.eventRecorder.Event(pbc, v1.EventTypeWarning, "BucketClaimInUse", msg)
+	if _, err := ctrl.setBucketClaimPhase(ctx, pbc, crdv1alpha1.BucketPhaseDeleting, reasonBucketClaimInUse, msg); err != nil && !k8s_errors.IsNotFound(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+func cascadeDelete(pbc *crdv1alpha1.PXBucketClaim) bool {
+	cascadeVal, ok := pbc.Annotations[cascadeDeleteKey]
+	if !ok {
+		return false
+	}
+	cascade, err := strconv.ParseBool(cascadeVal)
+	if err != nil {
+		logrus.Errorf("invalid value %s for %s, defaulting to false: %v", cascadeVal, cascadeDeleteKey, err)
+	}
+	return cascade
+}
+
+// enqueueAccessBucketClaim adds the PXBucketClaim referenced by a deleted
+// PXBucketAccess to the bucket work queue so that a protected claim
+// deletion can continue.
+func (ctrl *Controller) enqueueAccessBucketClaim(obj interface{}) {
+	if unknown, ok := obj.(cache.DeletedFinalStateUnknown); ok && unknown.Obj != nil {
+		obj = unknown.Obj
+	}
+	if access, ok := obj.(*crdv1alpha1.PXBucketAccess); ok && access.Spec.BucketClaimName != "" {
+		ctrl.bucketQueue.Add(access.Namespace + "/" + access.Spec.BucketClaimName)
+	}
+}
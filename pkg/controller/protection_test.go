@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/client/clientset/versioned/fake"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newProtectedBucketClaim(annotations map[string]string) *crdv1alpha1.PXBucketClaim {
+	now := metav1.Now()
+	return &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "claim",
+			Namespace:         "tenant-a",
+			Annotations:       annotations,
+			DeletionTimestamp: &now,
+			Finalizers:        []string{bucketClaimProtectionFinalizer},
+		},
+		Status: &crdv1alpha1.BucketClaimStatus{
+			Phase: crdv1alpha1.BucketPhaseProvisioning,
+		},
+	}
+}
+
+func newClaimAccess(name, claimName string) *crdv1alpha1.PXBucketAccess {
+	return &crdv1alpha1.PXBucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenant-a"},
+		Spec:       crdv1alpha1.BucketAccessSpec{BucketClaimName: claimName},
+	}
+}
+
+// newProtectionController returns a controller with the given accesses in its
+// access cache and all objects in a fake clientset
+func newProtectionController(t *testing.T, pbc *crdv1alpha1.PXBucketClaim, accesses ...*crdv1alpha1.PXBucketAccess) (*Controller, *fake.Clientset) {
+	accessIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{bucketClaimIndex: bucketClaimIndexFunc})
+	objs := []runtime.Object{pbc}
+	for _, access := range accesses {
+		if err := accessIndexer.Add(access); err != nil {
+			t.Fatalf("failed to add access: %v", err)
+		}
+		objs = append(objs, access)
+	}
+	client := fake.NewSimpleClientset(objs...)
+	return &Controller{
+		config:          &Config{},
+		k8sBucketClient: client,
+		eventRecorder:   record.NewFakeRecorder(10),
+		accessIndexer:   accessIndexer,
+		bucketStore:     cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc),
+		bucketQueue:     workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}, client
+}
+
+func TestCheckBucketClaimInUse(t *testing.T) {
+	testCases := []struct {
+		name            string
+		annotations     map[string]string
+		accesses        []*crdv1alpha1.PXBucketAccess
+		expectedInUse   bool
+		expectedDeleted []string
+		expectedReason  string
+	}{
+		{
+			name: "unreferenced claim",
+			accesses: []*crdv1alpha1.PXBucketAccess{
+				newClaimAccess("other-access", "other-claim"),
+			},
+		},
+		{
+			name: "referenced claim is blocked",
+			accesses: []*crdv1alpha1.PXBucketAccess{
+				newClaimAccess("access", "claim"),
+				newClaimAccess("other-access", "other-claim"),
+			},
+			expectedInUse:  true,
+			expectedReason: reasonBucketClaimInUse,
+		},
+		{
+			name:        "invalid cascade annotation is ignored",
+			annotations: map[string]string{cascadeDeleteKey: "yes please"},
+			accesses: []*crdv1alpha1.PXBucketAccess{
+				newClaimAccess("access", "claim"),
+			},
+			expectedInUse:  true,
+			expectedReason: reasonBucketClaimInUse,
+		},
+		{
+			name:        "cascade deletes referencing accesses",
+			annotations: map[string]string{cascadeDeleteKey: "true"},
+			accesses: []*crdv1alpha1.PXBucketAccess{
+				newClaimAccess("access", "claim"),
+				newClaimAccess("other-access", "other-claim"),
+			},
+			expectedInUse:   true,
+			expectedDeleted: []string{"access"},
+			expectedReason:  reasonProvisioning,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pbc := newProtectedBucketClaim(tc.annotations)
+			bucketClaimStatusFields(pbc).setPhase(crdv1alpha1.BucketPhaseProvisioning, reasonProvisioning, "provisioning")
+			ctrl, client := newProtectionController(t, pbc, tc.accesses...)
+
+			inUse, err := ctrl.checkBucketClaimInUse(context.Background(), pbc.DeepCopy())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if inUse != tc.expectedInUse {
+				t.Fatalf("expected in use %v, got %v", tc.expectedInUse, inUse)
+			}
+
+			for _, access := range tc.accesses {
+				_, err := client.ObjectV1alpha1().PXBucketAccesses(access.Namespace).Get(context.Background(), access.Name, metav1.GetOptions{})
+				deleted := false
+				for _, name := range tc.expectedDeleted {
+					deleted = deleted || name == access.Name
+				}
+				if deleted != k8s_errors.IsNotFound(err) {
+					t.Errorf("expected access %s to be deleted: %v, got error %v", access.Name, deleted, err)
+				}
+			}
+
+			updated, err := client.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.Background(), pbc.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get bucket claim: %v", err)
+			}
+			if tc.expectedReason == "" {
+				return
+			}
+			cond := meta.FindStatusCondition(updated.Status.Conditions, phaseConditions[updated.Status.Phase])
+			if cond == nil || cond.Reason != tc.expectedReason {
+				t.Errorf("expected reason %s, got %+v", tc.expectedReason, cond)
+			}
+		})
+	}
+}
+
+func TestCheckBucketClaimInUseRecordsOnce(t *testing.T) {
+	pbc := newProtectedBucketClaim(nil)
+	ctrl, client := newProtectionController(t, pbc, newClaimAccess("access", "claim"))
+	recorder := ctrl.eventRecorder.(*record.FakeRecorder)
+
+	for i := 0; i < 2; i++ {
+		current, err := client.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.Background(), pbc.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get bucket claim: %v", err)
+		}
+		if inUse, err := ctrl.checkBucketClaimInUse(context.Background(), current); err != nil || !inUse {
+			t.Fatalf("expected claim to be in use, got %v, %v", inUse, err)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected one event for repeated syncs, got %d", len(recorder.Events))
+	}
+}
+
+func TestBucketClaimFinalizerRemovedAfterLastAccess(t *testing.T) {
+	pbc := newProtectedBucketClaim(nil)
+	access := newClaimAccess("access", "claim")
+	ctrl, client := newProtectionController(t, pbc, access)
+
+	if inUse, err := ctrl.checkBucketClaimInUse(context.Background(), pbc.DeepCopy()); err != nil || !inUse {
+		t.Fatalf("expected claim to be in use, got %v, %v", inUse, err)
+	}
+
+	// Deleting the last access requeues the claim
+	if err := ctrl.accessIndexer.Delete(access); err != nil {
+		t.Fatalf("failed to delete access: %v", err)
+	}
+	ctrl.enqueueAccessBucketClaim(cache.DeletedFinalStateUnknown{Key: "tenant-a/access", Obj: access})
+	if ctrl.bucketQueue.Len() != 1 {
+		t.Fatalf("expected bucket claim to be requeued, got %d items", ctrl.bucketQueue.Len())
+	}
+	key, _ := ctrl.bucketQueue.Get()
+	if key != "tenant-a/claim" {
+		t.Errorf("expected tenant-a/claim to be requeued, got %v", key)
+	}
+
+	current, err := client.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.Background(), pbc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get bucket claim: %v", err)
+	}
+	if inUse, err := ctrl.checkBucketClaimInUse(context.Background(), current); err != nil || inUse {
+		t.Fatalf("expected claim not to be in use, got %v, %v", inUse, err)
+	}
+	if err := ctrl.deleteBucket(context.Background(), current); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := client.ObjectV1alpha1().PXBucketClaims(pbc.Namespace).Get(context.Background(), pbc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get bucket claim: %v", err)
+	}
+	if hasFinalizer(updated.Finalizers, bucketClaimProtectionFinalizer) {
+		t.Errorf("expected protection finalizer to be removed, got %v", updated.Finalizers)
+	}
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/libopenstorage/openstorage/api"
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/pkg/backend"
+	v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// credentialsHashKey is the annotation holding the hash of the data written
+// to a secret by the controller. Data that no longer matches it was changed
+// by someone else.
+const credentialsHashKey = commonObjectServiceKeyPrefix + "credentials-hash"
+
+// secretChange describes what applySecretData did to a secret
+type secretChange int
+
+const (
+	// secretUnchanged means the secret already held the data
+	secretUnchanged secretChange = iota
+	// secretCreated means the secret did not exist
+	secretCreated
+	// secretUpdated means the data written by the controller changed
+	secretUpdated
+	// secretRestored means the data was modified by someone else
+	secretRestored
+)
+
+// hashSecretData returns a hash of the secret data that does not depend on
+// the order of the keys
+func hashSecretData(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%d:%s%d:", len(k), k, len(data[k]))
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isSecretDataModified returns true if the data of the secret no longer
+// matches what the controller wrote. Secrets written before the hash was
+// recorded are never reported as modified.
+func isSecretDataModified(secret *v1.Secret) bool {
+	hash, ok := secret.Annotations[credentialsHashKey]
+	return ok && hash != hashSecretData(secret.Data)
+}
+
+// getSecretAccessKey returns the raw access key held by the secret if the
+// secret is intact
+func getSecretAccessKey(secret *v1.Secret) (string, string, bool) {
+	accessKeyID, secretAccessKey := string(secret.Data[accessKeyIDKey]), string(secret.Data[secretAccessKeyKey])
+	if accessKeyID == "" || secretAccessKey == "" || isSecretDataModified(secret) {
+		return "", "", false
+	}
+	return accessKeyID, secretAccessKey, true
+}
+
+// getLiveSecret returns a secret of the PXBucketAccess. A secret that is being
+// deleted by someone else is released and reported as not found, so that it
+// is created again.
+func (ctrl *Controller) getLiveSecret(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, name string) (*v1.Secret, error) {
+	secret, err := ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !isSecretOwnedBy(secret, pba) {
+		return nil, fmt.Errorf("secret %s/%s already exists and is not owned by bucket access %s", secret.Namespace, secret.Name, pba.Name)
+	}
+	if secret.DeletionTimestamp == nil {
+		return secret, nil
+	}
+
+	if hasFinalizer(secret.Finalizers, accessSecretFinalizer) {
+		secret.Finalizers = removeFinalizer(secret.Finalizers, accessSecretFinalizer)
+		if _, err := ctrl.k8sClient.CoreV1().Secrets(pba.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil && !k8s_errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return nil, k8s_errors.NewNotFound(v1.Resource("secrets"), name)
+}
+
+// recordSecretRepair emits an event if applySecretData repaired the secret
+func (ctrl *Controller) recordSecretRepair(pba *crdv1alpha1.PXBucketAccess, name string, change secretChange) {
+	switch change {
+	case secretCreated:
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretRepaired", fmt.Sprintf("credentials secret %s was deleted and has been recreated", name))
+	case secretRestored:
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretRepaired", fmt.Sprintf("credentials secret %s was modified and has been restored", name))
+	}
+}
+
+// enqueueSecretOwner enqueues the PXBucketAccess owning a secret written by
+// the controller, so that a deleted or modified secret is repaired
+func (ctrl *Controller) enqueueSecretOwner(obj interface{}) {
+	if unknown, ok := obj.(cache.DeletedFinalStateUnknown); ok && unknown.Obj != nil {
+		obj = unknown.Obj
+	}
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil || owner.Kind != "PXBucketAccess" || owner.APIVersion != crdv1alpha1.SchemeGroupVersion.String() {
+		return
+	}
+	ctrl.accessQueue.Add(secret.Namespace + "/" + owner.Name)
+}
+
+// ensureSourceCredentials makes sure the source secret of a granted
+// PXBucketAccess holds its access key. A lost source is restored from another
+// access sharing the key or from a Default format credentials secret, and
+// otherwise new credentials are issued.
+func (ctrl *Controller) ensureSourceCredentials(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, pbaclass *crdv1alpha1.PXBucketAccessClass) (*crdv1alpha1.PXBucketAccess, error) {
+	source, err := ctrl.getLiveSecret(ctx, pba, getCredentialsSourceName(pba))
+	if err == nil {
+		if _, _, ok := getSecretAccessKey(source); ok {
+			return pba, nil
+		}
+	} else if !k8s_errors.IsNotFound(err) {
+		errMsg := fmt.Sprintf("failed to get source credentials: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonCredentialsSecretFailed, errMsg)
+		return pba, err
+	}
+
+	accessKeyID, secretAccessKey, from := ctrl.findAccessKey(ctx, pba)
+	if from != "" {
+		if err := ctrl.writeSourceCredentials(ctx, pba, accessKeyID, secretAccessKey); err != nil {
+			errMsg := fmt.Sprintf("failed to restore source credentials: %v", err)
+			ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretError", errMsg)
+			ctrl.recordBucketAccessError(ctx, pba, reasonCredentialsSecretFailed, errMsg)
+			return pba, err
+		}
+		// Accesses granted before the source secret existed have no
+		// credentials format in their status and are migrated silently
+		if pba.Status.CredentialsFormat != "" {
+			ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretRepaired", fmt.Sprintf("source credentials secret %s was lost and has been restored from %s", getCredentialsSourceName(pba), from))
+		}
+		return pba, nil
+	}
+
+	return ctrl.reissueCredentials(ctx, pba, pbclass, pbaclass)
+}
+
+// findAccessKey looks for an intact copy of the access key of the
+// PXBucketAccess. It returns the key and the name of the secret holding it,
+// or an empty name if there is none.
+func (ctrl *Controller) findAccessKey(ctx context.Context, pba *crdv1alpha1.PXBucketAccess) (string, string, string) {
+	if pba.Status.AccessKeyId != "" {
+		siblings, err := ctrl.getAccountAccesses(pba)
+		if err != nil {
+			logrus.WithContext(ctx).Errorf("failed to list accesses sharing the account of bucket access %s/%s: %v", pba.Namespace, pba.Name, err)
+		}
+		for _, sibling := range siblings {
+			if sibling.DeletionTimestamp != nil || sibling.Status.AccessKeyId != pba.Status.AccessKeyId {
+				continue
+			}
+			source, err := ctrl.k8sClient.CoreV1().Secrets(sibling.Namespace).Get(ctx, getCredentialsSourceName(sibling), metav1.GetOptions{})
+			if err != nil || source.DeletionTimestamp != nil {
+				continue
+			}
+			if accessKeyID, secretAccessKey, ok := getSecretAccessKey(source); ok && accessKeyID == pba.Status.AccessKeyId {
+				return accessKeyID, secretAccessKey, source.Name
+			}
+		}
+	}
+
+	// The Default format holds the raw key
+	if pba.Status.CredentialsFormat != "" && pba.Status.CredentialsFormat != crdv1alpha1.CredentialsFormatDefault {
+		return "", "", ""
+	}
+	secret, err := ctrl.getLiveSecret(ctx, pba, getCredentialsSecretName(pba))
+	if err != nil {
+		return "", "", ""
+	}
+	accessKeyID, secretAccessKey, ok := getSecretAccessKey(secret)
+	if !ok || (pba.Status.AccessKeyId != "" && accessKeyID != pba.Status.AccessKeyId) {
+		return "", "", ""
+	}
+	return accessKeyID, secretAccessKey, secret.Name
+}
+
+// reissueCredentials issues new credentials for a PXBucketAccess whose access
+// key is lost. Backends with tracked keys rotate to a new key and keep the
+// lost one valid for the overlap window. Other backends grant access again.
+func (ctrl *Controller) reissueCredentials(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, pbaclass *crdv1alpha1.PXBucketAccessClass) (*crdv1alpha1.PXBucketAccess, error) {
+	status := pba.Status.DeepCopy()
+	var err error
+	if backendClient, backendErr := ctrl.getRotationBackend(pba.Status.BackendType); backendErr == nil {
+		err = ctrl.reissueAccessKey(ctx, pba, pbclass, backendClient)
+	} else {
+		err = ctrl.regrantAccess(ctx, pba, pbaclass)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("source credentials are lost and new credentials could not be issued: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsSecretError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonCredentialsSecretFailed, errMsg)
+		return pba, err
+	}
+	ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "CredentialsReissued", fmt.Sprintf("source credentials secret %s was lost, new credentials have been issued", getCredentialsSourceName(pba)))
+
+	if reflect.DeepEqual(status, pba.Status) {
+		return pba, nil
+	}
+	return ctrl.updateBucketAccessStatus(ctx, pba)
+}
+
+// reissueAccessKey rotates the PXBucketAccess to a new access key
+func (ctrl *Controller) reissueAccessKey(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass, backendClient *backend.Client) error {
+	period, err := getRotationPeriod(pba, pbclass)
+	if err != nil {
+		return err
+	}
+	overlap, err := getRotationOverlap(pbclass, period)
+	if err != nil {
+		return err
+	}
+	newest, err := ctrl.getNewestAccountKey(pba)
+	if err != nil {
+		return err
+	}
+	return ctrl.rotateAccessKey(ctx, pba, backendClient, newest, overlap)
+}
+
+// regrantAccess grants access to the bucket again and stores the returned
+// credentials
+func (ctrl *Controller) regrantAccess(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbaclass *crdv1alpha1.PXBucketAccessClass) error {
+	accessPolicy, err := renderAccessPolicy(pba, pbaclass, pba.Status.BucketId)
+	if err != nil {
+		return err
+	}
+	namespace, err := ctrl.k8sClient.CoreV1().Namespaces().Get(ctx, pba.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	resp, err := ctrl.bucketClient.AccessBucket(ctx, &api.BucketGrantAccessRequest{
+		BucketId:     pba.Status.BucketId,
+		AccountName:  getAccountName(namespace),
+		AccessPolicy: accessPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	return ctrl.writeSourceCredentials(ctx, pba, resp.Credentials.GetAccessKeyId(), resp.Credentials.GetSecretAccessKey())
+}
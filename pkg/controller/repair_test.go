@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsSecretDataModified(t *testing.T) {
+	data := map[string][]byte{
+		accessKeyIDKey:     []byte("AKIA"),
+		secretAccessKeyKey: []byte("secret"),
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{credentialsHashKey: hashSecretData(data)}},
+		Data: map[string][]byte{
+			secretAccessKeyKey: []byte("secret"),
+			accessKeyIDKey:     []byte("AKIA"),
+		},
+	}
+	if isSecretDataModified(secret) {
+		t.Errorf("expected secret with matching hash not to be modified")
+	}
+	if _, _, ok := getSecretAccessKey(secret); !ok {
+		t.Errorf("expected access key of intact secret")
+	}
+
+	secret.Data[secretAccessKeyKey] = []byte("changed")
+	if !isSecretDataModified(secret) {
+		t.Errorf("expected secret with changed data to be modified")
+	}
+	if _, _, ok := getSecretAccessKey(secret); ok {
+		t.Errorf("expected no access key from modified secret")
+	}
+
+	// Secrets written before the hash was recorded
+	secret.Annotations = nil
+	if isSecretDataModified(secret) {
+		t.Errorf("expected secret without hash not to be modified")
+	}
+
+	// Moving bytes between keys changes the hash
+	if hashSecretData(map[string][]byte{"a": []byte("bc")}) == hashSecretData(map[string][]byte{"ab": []byte("c")}) {
+		t.Errorf("expected different hashes for different data")
+	}
+}
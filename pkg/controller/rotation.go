@@ -0,0 +1,435 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/pkg/backend"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// rotationPeriodKey is a PXBucketClass parameter setting the default
+	// rotation period of the access keys of PXBucketAccesses, such as "2160h".
+	rotationPeriodKey = commonObjectServiceKeyPrefix + "credential-rotation-period"
+
+	// rotationOverlapKey is a PXBucketClass parameter setting how long a
+	// replaced access key stays valid after rotation.
+	rotationOverlapKey = commonObjectServiceKeyPrefix + "credential-rotation-overlap"
+
+	// rotateCredentialsKey is a PXBucketAccess annotation requesting an
+	// immediate rotation. The controller removes it once the key is rotated.
+	rotateCredentialsKey = commonObjectServiceKeyPrefix + "rotate-credentials"
+
+	minRotationPeriod      = time.Hour
+	defaultRotationOverlap = 24 * time.Hour
+
+	accessKeyIDKey     = "access-key-id"
+	secretAccessKeyKey = "secret-access-key"
+)
+
+// rotationDrivers are the backend types whose access keys can be rotated
+var rotationDrivers = map[string]bool{
+	"S3Driver": true,
+}
+
+// getRotationPeriod returns the rotation period of the PXBucketAccess.
+// Zero means the access key is not rotated periodically.
+func getRotationPeriod(pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass) (time.Duration, error) {
+	if pba.Spec.RotationPeriod != nil {
+		return pba.Spec.RotationPeriod.Duration, nil
+	}
+	period, ok := pbclass.Parameters[rotationPeriodKey]
+	if !ok {
+		return 0, nil
+	}
+	return parseRotationDuration(rotationPeriodKey, period)
+}
+
+// getRotationOverlap returns how long a replaced access key stays valid. It
+// defaults to 24h, or half the rotation period if that is shorter, and must
+// be shorter than the rotation period so that at most two keys exist.
+func getRotationOverlap(pbclass *crdv1alpha1.PXBucketClass, period time.Duration) (time.Duration, error) {
+	overlapValue, ok := pbclass.Parameters[rotationOverlapKey]
+	if !ok {
+		if period > 0 && period/2 < defaultRotationOverlap {
+			return period / 2, nil
+		}
+		return defaultRotationOverlap, nil
+	}
+
+	overlap, err := time.ParseDuration(overlapValue)
+	if err != nil || overlap < 0 {
+		return 0, fmt.Errorf("PXBucketClass parameter %s must be a non-negative duration, got %q", rotationOverlapKey, overlapValue)
+	}
+	if period > 0 && overlap >= period {
+		return 0, fmt.Errorf("rotation overlap %s must be shorter than the rotation period %s", overlap, period)
+	}
+	return overlap, nil
+}
+
+// parseRotationDuration parses a rotation period parameter
+func parseRotationDuration(key, value string) (time.Duration, error) {
+	period, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("PXBucketClass parameter %s must be a duration, got %q", key, value)
+	}
+	if err := validateRotationPeriod(period); err != nil {
+		return 0, fmt.Errorf("PXBucketClass parameter %s is invalid: %v", key, err)
+	}
+	return period, nil
+}
+
+// validateRotationPeriod checks that a rotation period is disabled or long enough
+func validateRotationPeriod(period time.Duration) error {
+	if period != 0 && period < minRotationPeriod {
+		return fmt.Errorf("rotation period %s must be 0 or at least %s", period, minRotationPeriod)
+	}
+	return nil
+}
+
+// rotationRequested returns true if the PXBucketAccess asks for an immediate rotation
+func rotationRequested(pba *crdv1alpha1.PXBucketAccess) bool {
+	return pba.Annotations[rotateCredentialsKey] != ""
+}
+
+// isNewerAccessKey returns true if other holds a different access key of the
+// same account that was created after the key of pba
+func isNewerAccessKey(pba, other *crdv1alpha1.PXBucketAccess) bool {
+	if other.UID == pba.UID || other.DeletionTimestamp != nil || other.Status == nil || !other.Status.AccessGranted {
+		return false
+	}
+	if other.Status.AccountId != pba.Status.AccountId || other.Status.BackendType != pba.Status.BackendType {
+		return false
+	}
+	if other.Status.AccessKeyId == "" || other.Status.AccessKeyId == pba.Status.AccessKeyId || other.Status.KeyCreationTime == nil {
+		return false
+	}
+	return pba.Status.KeyCreationTime == nil || other.Status.KeyCreationTime.After(pba.Status.KeyCreationTime.Time)
+}
+
+// getAccountAccesses returns the other PXBucketAccesses sharing the backend
+// account of pba. Accesses of a namespace share one account and its key.
+func (ctrl *Controller) getAccountAccesses(pba *crdv1alpha1.PXBucketAccess) ([]*crdv1alpha1.PXBucketAccess, error) {
+	accesses, err := ctrl.accessLister.PXBucketAccesses(pba.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	siblings := make([]*crdv1alpha1.PXBucketAccess, 0, len(accesses))
+	for _, access := range accesses {
+		if access.UID == pba.UID || access.Status == nil || access.Status.AccountId != pba.Status.AccountId || access.Status.BackendType != pba.Status.BackendType {
+			continue
+		}
+		siblings = append(siblings, access)
+	}
+	return siblings, nil
+}
+
+// getNewestAccountKey returns the PXBucketAccess holding the newest access key
+// of the account of pba if that key is newer than the key of pba
+func (ctrl *Controller) getNewestAccountKey(pba *crdv1alpha1.PXBucketAccess) (*crdv1alpha1.PXBucketAccess, error) {
+	siblings, err := ctrl.getAccountAccesses(pba)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *crdv1alpha1.PXBucketAccess
+	for _, sibling := range siblings {
+		if !isNewerAccessKey(pba, sibling) {
+			continue
+		}
+		if newest == nil || sibling.Status.KeyCreationTime.After(newest.Status.KeyCreationTime.Time) {
+			newest = sibling
+		}
+	}
+	return newest, nil
+}
+
+// isAccessKeyInUse returns true if another PXBucketAccess of the account still
+// uses the access key
+func (ctrl *Controller) isAccessKeyInUse(pba *crdv1alpha1.PXBucketAccess, accessKeyID string) (bool, error) {
+	siblings, err := ctrl.getAccountAccesses(pba)
+	if err != nil {
+		return false, err
+	}
+	for _, sibling := range siblings {
+		if sibling.DeletionTimestamp == nil && sibling.Status.AccessKeyId == accessKeyID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getRotationBackend returns the backend client used to manage the access keys of the PXBucketAccess
+func (ctrl *Controller) getRotationBackend(backendType string) (*backend.Client, error) {
+	if !rotationDrivers[backendType] {
+		return nil, fmt.Errorf("backend %s does not support credential rotation", backendType)
+	}
+	backendClient, ok := ctrl.config.Backends[backendType]
+	if !ok {
+		return nil, fmt.Errorf("no admin credentials configured for backend %s", backendType)
+	}
+	return backendClient, nil
+}
+
+// trackAccessKey records the ID and creation time of the access key of a
+// PXBucketAccess granted before rotation was supported
+func (ctrl *Controller) trackAccessKey(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, backendClient *backend.Client) error {
+	accessKeyID, _, err := ctrl.getSourceCredentials(ctx, pba)
+	if err != nil {
+		return fmt.Errorf("failed to get source credentials: %v", err)
+	}
+	keys, err := backendClient.ListAccessKeys(ctx, pba.Status.AccountId)
+	if err != nil {
+		return err
+	}
+
+	created := metav1.Now()
+	if createDate, ok := keys[accessKeyID]; ok {
+		created = metav1.NewTime(createDate)
+	}
+	pba.Status.AccessKeyId = accessKeyID
+	pba.Status.KeyCreationTime = &created
+	return nil
+}
+
+// checkGrantedAccessKey returns the access key to store for a new grant. The
+// driver hands out the first key it created for an account, which may have
+// been replaced by a rotation since. In that case the newest key of the
+// account is used, or a new key is created.
+func (ctrl *Controller) checkGrantedAccessKey(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, accessKeyID, secretAccessKey string) (string, string, *metav1.Time, error) {
+	backendClient, err := ctrl.getRotationBackend(pba.Status.BackendType)
+	if err != nil {
+		// Keys of this backend are not tracked
+		return accessKeyID, secretAccessKey, nil, nil
+	}
+
+	keys, err := backendClient.ListAccessKeys(ctx, pba.Status.AccountId)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if createDate, ok := keys[accessKeyID]; ok {
+		created := metav1.NewTime(createDate)
+		return accessKeyID, secretAccessKey, &created, nil
+	}
+
+	newest, err := ctrl.getNewestAccountKey(pba)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if newest != nil {
+		accessKeyID, secretAccessKey, err = ctrl.getSourceCredentials(ctx, newest)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to get credentials of bucket access %s: %v", newest.Name, err)
+		}
+		return accessKeyID, secretAccessKey, newest.Status.KeyCreationTime, nil
+	}
+
+	accessKeyID, secretAccessKey, err = backendClient.CreateAccessKey(ctx, pba.Status.AccountId)
+	if err != nil {
+		return "", "", nil, err
+	}
+	created := metav1.Now()
+	return accessKeyID, secretAccessKey, &created, nil
+}
+
+// reconcileCredentials rotates the access key of a granted PXBucketAccess when
+// it is due, when rotation is requested or when another access of the account
+// already moved to a newer key, and revokes replaced keys after the overlap.
+func (ctrl *Controller) reconcileCredentials(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, pbclass *crdv1alpha1.PXBucketClass) (*crdv1alpha1.PXBucketAccess, error) {
+	period, err := getRotationPeriod(pba, pbclass)
+	var overlap time.Duration
+	if err == nil {
+		overlap, err = getRotationOverlap(pbclass, period)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid credential rotation settings: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RotateCredentialsError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonRotateCredentialsFailed, errMsg)
+		return pba, err
+	}
+
+	backendClient, err := ctrl.getRotationBackend(pba.Status.BackendType)
+	if err != nil {
+		if period == 0 && !rotationRequested(pba) {
+			// Nothing to rotate and keys of this backend are not tracked
+			return pba, nil
+		}
+		errMsg := fmt.Sprintf("unable to rotate credentials: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RotateCredentialsError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonRotateCredentialsFailed, errMsg)
+		return pba, err
+	}
+
+	status := pba.Status.DeepCopy()
+	if pba.Status.AccessKeyId == "" {
+		if err := ctrl.trackAccessKey(ctx, pba, backendClient); err != nil {
+			errMsg := fmt.Sprintf("unable to track access key: %v", err)
+			ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RotateCredentialsError", errMsg)
+			ctrl.recordBucketAccessError(ctx, pba, reasonRotateCredentialsFailed, errMsg)
+			return pba, err
+		}
+	}
+
+	if err := ctrl.revokePreviousAccessKey(ctx, pba, backendClient, false); err != nil {
+		errMsg := fmt.Sprintf("failed to revoke previous access key: %v", err)
+		ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RotateCredentialsError", errMsg)
+		ctrl.recordBucketAccessError(ctx, pba, reasonRotateCredentialsFailed, errMsg)
+		return pba, err
+	}
+
+	newest, err := ctrl.getNewestAccountKey(pba)
+	if err != nil {
+		return pba, err
+	}
+	now := metav1.Now()
+	due := rotationRequested(pba) || (period > 0 && !now.Time.Before(pba.Status.KeyCreationTime.Add(period)))
+	if newest != nil || due {
+		if err := ctrl.rotateAccessKey(ctx, pba, backendClient, newest, overlap); err != nil {
+			errMsg := fmt.Sprintf("failed to rotate credentials: %v", err)
+			ctrl.eventRecorder.Event(pba, v1.EventTypeWarning, "RotateCredentialsError", errMsg)
+			ctrl.recordBucketAccessError(ctx, pba, reasonRotateCredentialsFailed, errMsg)
+			return pba, err
+		}
+	}
+
+	if period > 0 {
+		next := metav1.NewTime(pba.Status.KeyCreationTime.Add(period))
+		pba.Status.NextRotationTime = &next
+	} else {
+		pba.Status.NextRotationTime = nil
+	}
+	if pba.Status.Phase != crdv1alpha1.BucketPhaseReady {
+		bucketAccessStatusFields(pba).setPhase(crdv1alpha1.BucketPhaseReady, reasonAccessGranted, fmt.Sprintf("access granted to bucket %s", pba.Status.BucketId))
+	}
+	if !reflect.DeepEqual(status, pba.Status) {
+		updated, err := ctrl.updateBucketAccessStatus(ctx, pba)
+		if err != nil {
+			return pba, err
+		}
+		pba = updated
+	}
+
+	if rotationRequested(pba) {
+		pba, err = ctrl.patchBucketAccessMetadata(ctx, pba, func(meta *metav1.ObjectMeta) {
+			delete(meta.Annotations, rotateCredentialsKey)
+		})
+		if err != nil {
+			return pba, err
+		}
+	}
+	return pba, nil
+}
+
+// rotateAccessKey moves the PXBucketAccess to the newer key of another access
+// of the account or, if there is none, to a newly created key. The replaced
+// key stays valid for the overlap window.
+func (ctrl *Controller) rotateAccessKey(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, backendClient *backend.Client, newest *crdv1alpha1.PXBucketAccess, overlap time.Duration) error {
+	// At most two keys may exist per account. A second rotation within the
+	// overlap window revokes the oldest key right away.
+	if err := ctrl.revokePreviousAccessKey(ctx, pba, backendClient, true); err != nil {
+		return err
+	}
+
+	var accessKeyID, secretAccessKey string
+	var created metav1.Time
+	var err error
+	if newest != nil {
+		accessKeyID, secretAccessKey, err = ctrl.getSourceCredentials(ctx, newest)
+		if err != nil {
+			return fmt.Errorf("failed to get credentials of bucket access %s: %v", newest.Name, err)
+		}
+		if accessKeyID != newest.Status.AccessKeyId {
+			return fmt.Errorf("source credentials of bucket access %s are not yet updated", newest.Name)
+		}
+		created = *newest.Status.KeyCreationTime
+	} else {
+		accessKeyID, secretAccessKey, err = backendClient.CreateAccessKey(ctx, pba.Status.AccountId)
+		if err != nil {
+			return err
+		}
+		created = metav1.Now()
+	}
+
+	if err := ctrl.writeSourceCredentials(ctx, pba, accessKeyID, secretAccessKey); err != nil {
+		if newest == nil {
+			if delErr := backendClient.DeleteAccessKey(ctx, pba.Status.AccountId, accessKeyID); delErr != nil {
+				logrus.WithContext(ctx).Errorf("failed to delete unused access key %s: %v", accessKeyID, delErr)
+			}
+		}
+		return fmt.Errorf("failed to update source credentials: %v", err)
+	}
+
+	revocation := metav1.NewTime(time.Now().Add(overlap))
+	pba.Status.PreviousAccessKeyId = pba.Status.AccessKeyId
+	pba.Status.PreviousKeyRevocationTime = &revocation
+	pba.Status.AccessKeyId = accessKeyID
+	pba.Status.KeyCreationTime = &created
+	ctrl.eventRecorder.Event(pba, v1.EventTypeNormal, "CredentialsRotated", fmt.Sprintf("access key rotated, previous key %s is revoked at %s", pba.Status.PreviousAccessKeyId, revocation.Format(time.RFC3339)))
+
+	if newest == nil {
+		// Move the other accesses of the account to the new key
+		siblings, err := ctrl.getAccountAccesses(pba)
+		if err != nil {
+			return err
+		}
+		for _, sibling := range siblings {
+			ctrl.enqueueAccessWork(sibling)
+		}
+	}
+	return nil
+}
+
+// revokePreviousAccessKey revokes the key replaced by the last rotation once
+// the overlap window has passed, or right away if force is set. Keys still
+// used by another access of the account are left to that access.
+func (ctrl *Controller) revokePreviousAccessKey(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, backendClient *backend.Client, force bool) error {
+	if pba.Status.PreviousAccessKeyId == "" {
+		return nil
+	}
+	if !force && pba.Status.PreviousKeyRevocationTime != nil && time.Now().Before(pba.Status.PreviousKeyRevocationTime.Time) {
+		return nil
+	}
+
+	inUse, err := ctrl.isAccessKeyInUse(pba, pba.Status.PreviousAccessKeyId)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		logrus.WithContext(ctx).Infof("previous access key %s of bucket access %s/%s is still in use by another bucket access", pba.Status.PreviousAccessKeyId, pba.Namespace, pba.Name)
+	} else {
+		if err := backendClient.DeleteAccessKey(ctx, pba.Status.AccountId, pba.Status.PreviousAccessKeyId); err != nil {
+			return err
+		}
+		ctrl.eventRecorder.Event(pba, v1.EventTypeNormal, "CredentialsRevoked", fmt.Sprintf("previous access key %s revoked", pba.Status.PreviousAccessKeyId))
+	}
+
+	pba.Status.PreviousAccessKeyId = ""
+	pba.Status.PreviousKeyRevocationTime = nil
+	return nil
+}
+
+// scheduleCredentialsRotation requeues the PXBucketAccess for its next
+// rotation or revocation of its previous key
+func (ctrl *Controller) scheduleCredentialsRotation(key string, pba *crdv1alpha1.PXBucketAccess) {
+	if pba.Status == nil {
+		return
+	}
+
+	var next *metav1.Time
+	for _, t := range []*metav1.Time{pba.Status.NextRotationTime, pba.Status.PreviousKeyRevocationTime} {
+		if t != nil && (next == nil || t.Before(next)) {
+			next = t
+		}
+	}
+	if next == nil {
+		return
+	}
+	ctrl.accessQueue.AddAfter(key, time.Until(next.Time))
+}
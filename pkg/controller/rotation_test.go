@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGetRotationSettings(t *testing.T) {
+	class := &crdv1alpha1.PXBucketClass{
+		Parameters: map[string]string{rotationPeriodKey: "720h"},
+	}
+	pba := &crdv1alpha1.PXBucketAccess{}
+
+	period, err := getRotationPeriod(pba, class)
+	if err != nil || period != 720*time.Hour {
+		t.Fatalf("expected class rotation period, got %s: %v", period, err)
+	}
+
+	pba.Spec.RotationPeriod = &metav1.Duration{Duration: 2 * time.Hour}
+	period, err = getRotationPeriod(pba, class)
+	if err != nil || period != 2*time.Hour {
+		t.Fatalf("expected access rotation period, got %s: %v", period, err)
+	}
+
+	// The default overlap is capped to half the rotation period
+	overlap, err := getRotationOverlap(class, period)
+	if err != nil || overlap != time.Hour {
+		t.Fatalf("expected overlap of 1h, got %s: %v", overlap, err)
+	}
+
+	class.Parameters[rotationOverlapKey] = "2h"
+	if _, err := getRotationOverlap(class, period); err == nil {
+		t.Fatalf("expected error for overlap not shorter than the rotation period")
+	}
+
+	class.Parameters[rotationPeriodKey] = "10m"
+	if _, err := getRotationPeriod(&crdv1alpha1.PXBucketAccess{}, class); err == nil {
+		t.Fatalf("expected error for rotation period below the minimum")
+	}
+}
+
+func TestIsNewerAccessKey(t *testing.T) {
+	older := metav1.NewTime(time.Now().Add(-time.Hour))
+	newer := metav1.Now()
+	newAccess := func(uid, keyID string, created *metav1.Time) *crdv1alpha1.PXBucketAccess {
+		return &crdv1alpha1.PXBucketAccess{
+			ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid)},
+			Status: &crdv1alpha1.BucketAccessStatus{
+				AccessGranted:   true,
+				AccountId:       "px-os-account-1",
+				BackendType:     "S3Driver",
+				AccessKeyId:     keyID,
+				KeyCreationTime: created,
+			},
+		}
+	}
+
+	pba := newAccess("a", "key-1", &older)
+	if !isNewerAccessKey(pba, newAccess("b", "key-2", &newer)) {
+		t.Errorf("expected newer key of another access to be used")
+	}
+	if isNewerAccessKey(pba, newAccess("b", "key-1", &newer)) {
+		t.Errorf("expected same key to be ignored")
+	}
+	if isNewerAccessKey(newAccess("a", "key-2", &newer), newAccess("b", "key-1", &older)) {
+		t.Errorf("expected older key to be ignored")
+	}
+
+	other := newAccess("b", "key-2", &newer)
+	other.Status.AccountId = "px-os-account-2"
+	if isNewerAccessKey(pba, other) {
+		t.Errorf("expected key of another account to be ignored")
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels set on the secrets written for a PXBucketAccess
+const (
+	managedByLabel       = "app.kubernetes.io/managed-by"
+	managedByValue       = "px-object-controller"
+	bucketAccessLabel    = commonObjectServiceKeyPrefix + "bucket-access"
+	bucketClaimLabel     = commonObjectServiceKeyPrefix + "bucket-claim"
+	bucketClassLabel     = commonObjectServiceKeyPrefix + "bucket-class"
+	backendTypeLabel     = commonObjectServiceKeyPrefix + "backend-type"
+	credentialsKindLabel = commonObjectServiceKeyPrefix + "credentials-kind"
+
+	credentialsKindRendered = "rendered"
+	credentialsKindSource   = "source"
+)
+
+// getSecretLabels returns the labels of a secret written for the PXBucketAccess.
+// Values that are not valid label values, such as long names, are left out.
+func getSecretLabels(pba *crdv1alpha1.PXBucketAccess, kind string) map[string]string {
+	labels := map[string]string{
+		managedByLabel:       managedByValue,
+		credentialsKindLabel: kind,
+	}
+	values := map[string]string{
+		bucketAccessLabel: pba.Name,
+		bucketClaimLabel:  pba.Spec.BucketClaimName,
+		bucketClassLabel:  pba.Spec.BucketClassName,
+	}
+	if pba.Status != nil {
+		values[backendTypeLabel] = pba.Status.BackendType
+	}
+	for key, value := range values {
+		if value != "" && len(validation.IsValidLabelValue(value)) == 0 {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// newBucketAccessOwnerReference returns a controller reference to the PXBucketAccess
+func newBucketAccessOwnerReference(pba *crdv1alpha1.PXBucketAccess) metav1.OwnerReference {
+	return *metav1.NewControllerRef(pba, crdv1alpha1.SchemeGroupVersion.WithKind("PXBucketAccess"))
+}
+
+// isSecretOwnedBy returns true if the secret belongs to the PXBucketAccess.
+// Secrets written before owner references were set are recognized by the
+// controller finalizer and their default name.
+func isSecretOwnedBy(secret *v1.Secret, pba *crdv1alpha1.PXBucketAccess) bool {
+	if owner := metav1.GetControllerOf(secret); owner != nil {
+		return owner.UID == pba.UID
+	}
+	if !hasFinalizer(secret.Finalizers, accessSecretFinalizer) {
+		return false
+	}
+	return secret.Name == getDefaultCredentialsSecretName(pba) || secret.Name == getCredentialsSourceName(pba)
+}
+
+// setSecretOwnership adds the owner reference and labels of the PXBucketAccess
+// to the secret. It returns true if the secret was changed.
+func setSecretOwnership(secret *v1.Secret, pba *crdv1alpha1.PXBucketAccess, kind string) bool {
+	changed := false
+	if metav1.GetControllerOf(secret) == nil {
+		secret.OwnerReferences = append(secret.OwnerReferences, newBucketAccessOwnerReference(pba))
+		changed = true
+	}
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	for key, value := range getSecretLabels(pba, kind) {
+		if secret.Labels[key] != value {
+			secret.Labels[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
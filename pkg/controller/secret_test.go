@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsSecretOwnedBy(t *testing.T) {
+	pba := &crdv1alpha1.PXBucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "analytics", Namespace: "team-a", UID: "uid-1"},
+		Spec:       crdv1alpha1.BucketAccessSpec{CredentialsSecretName: "custom"},
+	}
+	other := pba.DeepCopy()
+	other.UID = "uid-2"
+
+	owned := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:            "custom",
+		OwnerReferences: []metav1.OwnerReference{newBucketAccessOwnerReference(pba)},
+	}}
+	if !isSecretOwnedBy(owned, pba) {
+		t.Errorf("expected secret with owner reference to be owned")
+	}
+	if isSecretOwnedBy(owned, other) {
+		t.Errorf("expected secret of another bucket access not to be owned")
+	}
+
+	// Secrets written before owner references were set
+	legacy := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:       "px-os-credentials-analytics",
+		Finalizers: []string{accessSecretFinalizer},
+	}}
+	if !isSecretOwnedBy(legacy, pba) {
+		t.Errorf("expected legacy secret with the default name to be owned")
+	}
+	legacy.Name = "custom"
+	if isSecretOwnedBy(legacy, pba) {
+		t.Errorf("expected legacy secret with another name not to be owned")
+	}
+
+	unmanaged := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "custom"}}
+	if isSecretOwnedBy(unmanaged, pba) {
+		t.Errorf("expected unmanaged secret not to be owned")
+	}
+}
+
+func TestGetSecretLabels(t *testing.T) {
+	pba := &crdv1alpha1.PXBucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 64)},
+		Spec: crdv1alpha1.BucketAccessSpec{
+			BucketClassName: "s3",
+			BucketClaimName: "claim",
+		},
+		Status: &crdv1alpha1.BucketAccessStatus{BackendType: "S3Driver"},
+	}
+
+	labels := getSecretLabels(pba, credentialsKindRendered)
+	if labels[bucketClassLabel] != "s3" || labels[bucketClaimLabel] != "claim" || labels[backendTypeLabel] != "S3Driver" || labels[managedByLabel] != managedByValue {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+	if _, ok := labels[bucketAccessLabel]; ok {
+		t.Errorf("expected name longer than a label value to be left out")
+	}
+}
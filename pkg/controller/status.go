@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"context"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Reasons used for the standard PXBucketClaim and PXBucketAccess conditions
+const (
+	reasonProvisioning            = "Provisioning"
+	reasonBucketProvisioned       = "BucketProvisioned"
+	reasonAccessGranted           = "AccessGranted"
+	reasonDeleting                = "Deleting"
+	reasonBucketClassMissing      = "BucketClassMissing"
+	reasonInvalidBucketClass      = "InvalidBucketClass"
+	reasonCreateBucketFailed      = "CreateBucketFailed"
+	reasonDeleteBucketFailed      = "DeleteBucketFailed"
+	reasonBucketClaimMissing      = "BucketClaimMissing"
+	reasonGrantAccessFailed       = "GrantAccessFailed"
+	reasonRevokeAccessFailed      = "RevokeAccessFailed"
+	reasonWaitingForBucket        = "WaitingForBucket"
+	reasonBucketClaimInUse        = "BucketClaimInUse"
+	reasonBucketAccessDenied      = "BucketAccessDenied"
+	reasonBucketBound             = "BucketBound"
+	reasonBucketBindFailed        = "BucketBindFailed"
+	reasonBucketAdopted           = "BucketAdopted"
+	reasonBucketAdoptFailed       = "BucketAdoptFailed"
+	reasonInvalidBucketName       = "InvalidBucketName"
+	reasonBucketClaimDeleting     = "BucketClaimDeleting"
+	reasonAdminCredentialsMissing = "AdminCredentialsMissing"
+
+	reasonBucketAccessClassMissing = "BucketAccessClassMissing"
+	reasonInvalidAccessPolicy      = "InvalidAccessPolicy"
+	reasonAnonymousAccessFailed    = "AnonymousAccessFailed"
+	reasonRotateCredentialsFailed  = "RotateCredentialsFailed"
+	reasonCredentialsSecretFailed  = "CredentialsSecretFailed"
+
+	reasonBucketLost      = "BucketLost"
+	reasonBucketFound     = "BucketFound"
+	reasonBucketRecreated = "BucketRecreated"
+	reasonAccessKeyLost   = "AccessKeyLost"
+	reasonAccessVerified  = "AccessVerified"
+)
+
+// statusFields points at the status fields shared by
+// BucketClaimStatus and BucketAccessStatus.
+type statusFields struct {
+	generation         int64
+	phase              *crdv1alpha1.BucketPhase
+	lastTransitionTime **metav1.Time
+	observedGeneration *int64
+	lastError          *string
+	conditions         *[]metav1.Condition
+}
+
+func bucketClaimStatusFields(pbc *crdv1alpha1.PXBucketClaim) *statusFields {
+	if pbc.Status == nil {
+		pbc.Status = &crdv1alpha1.BucketClaimStatus{}
+	}
+	return &statusFields{
+		generation:         pbc.Generation,
+		phase:              &pbc.Status.Phase,
+		lastTransitionTime: &pbc.Status.LastTransitionTime,
+		observedGeneration: &pbc.Status.ObservedGeneration,
+		lastError:          &pbc.Status.LastError,
+		conditions:         &pbc.Status.Conditions,
+	}
+}
+
+func bucketAccessStatusFields(pba *crdv1alpha1.PXBucketAccess) *statusFields {
+	if pba.Status == nil {
+		pba.Status = &crdv1alpha1.BucketAccessStatus{}
+	}
+	return &statusFields{
+		generation:         pba.Generation,
+		phase:              &pba.Status.Phase,
+		lastTransitionTime: &pba.Status.LastTransitionTime,
+		observedGeneration: &pba.Status.ObservedGeneration,
+		lastError:          &pba.Status.LastError,
+		conditions:         &pba.Status.Conditions,
+	}
+}
+
+// phaseConditions maps each phase to the condition type that is true in that phase
+var phaseConditions = map[crdv1alpha1.BucketPhase]string{
+	crdv1alpha1.BucketPhaseProvisioning: crdv1alpha1.ConditionProvisioning,
+	crdv1alpha1.BucketPhaseReady:        crdv1alpha1.ConditionReady,
+	crdv1alpha1.BucketPhaseFailed:       crdv1alpha1.ConditionFailed,
+	crdv1alpha1.BucketPhaseDeleting:     crdv1alpha1.ConditionDeleting,
+}
+
+func (s *statusFields) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(s.conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: s.generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// setPhase moves the object to the given phase and sets every standard
+// condition so that only the one matching the phase is true.
+func (s *statusFields) setPhase(phase crdv1alpha1.BucketPhase, reason, message string) {
+	if *s.phase != phase {
+		now := metav1.Now()
+		*s.phase = phase
+		*s.lastTransitionTime = &now
+	}
+	*s.observedGeneration = s.generation
+
+	for _, conditionType := range []string{
+		crdv1alpha1.ConditionReady,
+		crdv1alpha1.ConditionProvisioning,
+		crdv1alpha1.ConditionFailed,
+		crdv1alpha1.ConditionDeleting,
+	} {
+		if phaseConditions[phase] == conditionType {
+			s.setCondition(conditionType, metav1.ConditionTrue, reason, message)
+		} else {
+			s.setCondition(conditionType, metav1.ConditionFalse, reason, "")
+		}
+	}
+
+	if phase == crdv1alpha1.BucketPhaseReady {
+		*s.lastError = ""
+	}
+
+	if meta.IsStatusConditionTrue(*s.conditions, crdv1alpha1.ConditionWaitingForBucket) {
+		s.setCondition(crdv1alpha1.ConditionWaitingForBucket, metav1.ConditionFalse, reason, "")
+	}
+}
+
+// setFailed records a failed reconcile attempt. An object that is being
+// deleted stays in the Deleting phase so that the pending deletion is
+// still visible. It returns false if the same failure is already recorded,
+// so that repeated attempts do not write the status. The number of attempts
+// is tracked by the rate limiter of the work queue.
+func (s *statusFields) setFailed(reason, message string) bool {
+	failed := meta.FindStatusCondition(*s.conditions, crdv1alpha1.ConditionFailed)
+	if failed != nil && failed.Status == metav1.ConditionTrue && failed.Reason == reason && failed.Message == message &&
+		*s.lastError == message && *s.observedGeneration == s.generation {
+		return false
+	}
+
+	if *s.phase == crdv1alpha1.BucketPhaseDeleting {
+		*s.observedGeneration = s.generation
+		s.setCondition(crdv1alpha1.ConditionFailed, metav1.ConditionTrue, reason, message)
+	} else {
+		s.setPhase(crdv1alpha1.BucketPhaseFailed, reason, message)
+	}
+	*s.lastError = message
+	return true
+}
+
+// updateBucketClaimStatus writes the status of the PXBucketClaim through the status
+// subresource. On conflict the status is copied onto the latest object and retried.
+func (ctrl *Controller) updateBucketClaimStatus(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim) (*crdv1alpha1.PXBucketClaim, error) {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketClaims(pbc.Namespace)
+	status := pbc.Status
+	var updated *crdv1alpha1.PXBucketClaim
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		updated, err = client.UpdateStatus(ctx, pbc, metav1.UpdateOptions{})
+		if k8s_errors.IsConflict(err) {
+			latest, getErr := client.Get(ctx, pbc.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			latest.Status = status
+			pbc = latest
+		}
+		return err
+	})
+	return updated, err
+}
+
+// updateBucketAccessStatus writes the status of the PXBucketAccess through the status
+// subresource. On conflict the status is copied onto the latest object and retried.
+func (ctrl *Controller) updateBucketAccessStatus(ctx context.Context, pba *crdv1alpha1.PXBucketAccess) (*crdv1alpha1.PXBucketAccess, error) {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBucketAccesses(pba.Namespace)
+	status := pba.Status
+	var updated *crdv1alpha1.PXBucketAccess
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		updated, err = client.UpdateStatus(ctx, pba, metav1.UpdateOptions{})
+		if k8s_errors.IsConflict(err) {
+			latest, getErr := client.Get(ctx, pba.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			latest.Status = status
+			pba = latest
+		}
+		return err
+	})
+	return updated, err
+}
+
+// updateBucketStatus writes the status of the PXBucket through the status
+// subresource. On conflict the status is copied onto the latest object and retried.
+func (ctrl *Controller) updateBucketStatus(ctx context.Context, pb *crdv1alpha1.PXBucket) (*crdv1alpha1.PXBucket, error) {
+	client := ctrl.k8sBucketClient.ObjectV1alpha1().PXBuckets()
+	status := pb.Status
+	var updated *crdv1alpha1.PXBucket
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		updated, err = client.UpdateStatus(ctx, pb, metav1.UpdateOptions{})
+		if k8s_errors.IsConflict(err) {
+			latest, getErr := client.Get(ctx, pb.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			latest.Status = status
+			pb = latest
+		}
+		return err
+	})
+	return updated, err
+}
+
+// setBucketClaimPhase moves the PXBucketClaim to the given phase and persists the status.
+// On success the updated object is returned, otherwise the given object is returned.
+func (ctrl *Controller) setBucketClaimPhase(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, phase crdv1alpha1.BucketPhase, reason, message string) (*crdv1alpha1.PXBucketClaim, error) {
+	bucketClaimStatusFields(pbc).setPhase(phase, reason, message)
+	updated, err := ctrl.updateBucketClaimStatus(ctx, pbc)
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("failed to update status of bucketclaim %s/%s: %v", pbc.Namespace, pbc.Name, err)
+		return pbc, err
+	}
+	return updated, nil
+}
+
+// recordBucketClaimError marks the PXBucketClaim as failed. Status update
+// errors are logged only, as the original error is what gets retried.
+func (ctrl *Controller) recordBucketClaimError(ctx context.Context, pbc *crdv1alpha1.PXBucketClaim, reason, message string) {
+	if !bucketClaimStatusFields(pbc).setFailed(reason, message) {
+		return
+	}
+	if _, err := ctrl.updateBucketClaimStatus(ctx, pbc); err != nil && !k8s_errors.IsNotFound(err) {
+		logrus.WithContext(ctx).Errorf("failed to record error on bucketclaim %s/%s: %v", pbc.Namespace, pbc.Name, err)
+	}
+}
+
+// setBucketAccessPhase moves the PXBucketAccess to the given phase and persists the status.
+// On success the updated object is returned, otherwise the given object is returned.
+func (ctrl *Controller) setBucketAccessPhase(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, phase crdv1alpha1.BucketPhase, reason, message string) (*crdv1alpha1.PXBucketAccess, error) {
+	bucketAccessStatusFields(pba).setPhase(phase, reason, message)
+	updated, err := ctrl.updateBucketAccessStatus(ctx, pba)
+	if err != nil {
+		logrus.WithContext(ctx).Errorf("failed to update status of bucketaccess %s/%s: %v", pba.Namespace, pba.Name, err)
+		return pba, err
+	}
+	return updated, nil
+}
+
+// recordBucketAccessError marks the PXBucketAccess as failed. Status update
+// errors are logged only, as the original error is what gets retried.
+func (ctrl *Controller) recordBucketAccessError(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, reason, message string) {
+	if !bucketAccessStatusFields(pba).setFailed(reason, message) {
+		return
+	}
+	if _, err := ctrl.updateBucketAccessStatus(ctx, pba); err != nil && !k8s_errors.IsNotFound(err) {
+		logrus.WithContext(ctx).Errorf("failed to record error on bucketaccess %s/%s: %v", pba.Namespace, pba.Name, err)
+	}
+}
+
+// setBucketAccessWaiting marks the PXBucketAccess as waiting for its PXBucketClaim.
+// The status is only written once so that repeated syncs do not cause update storms.
+func (ctrl *Controller) setBucketAccessWaiting(ctx context.Context, pba *crdv1alpha1.PXBucketAccess, message string) {
+	if pba.Status != nil && meta.IsStatusConditionTrue(pba.Status.Conditions, crdv1alpha1.ConditionWaitingForBucket) {
+		return
+	}
+
+	s := bucketAccessStatusFields(pba)
+	s.setPhase(crdv1alpha1.BucketPhasePending, reasonWaitingForBucket, message)
+	s.setCondition(crdv1alpha1.ConditionWaitingForBucket, metav1.ConditionTrue, reasonWaitingForBucket, message)
+	if _, err := ctrl.updateBucketAccessStatus(ctx, pba); err != nil && !k8s_errors.IsNotFound(err) {
+		logrus.WithContext(ctx).Errorf("failed to update status of bucketaccess %s/%s: %v", pba.Namespace, pba.Name, err)
+	}
+}
@@ -0,0 +1,321 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	standardConditions = []string{
+		crdv1alpha1.ConditionReady,
+		crdv1alpha1.ConditionProvisioning,
+		crdv1alpha1.ConditionFailed,
+		crdv1alpha1.ConditionDeleting,
+	}
+	oldTransitionTime = metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+)
+
+func TestSetPhase(t *testing.T) {
+	testCases := []struct {
+		name               string
+		status             *crdv1alpha1.BucketClaimStatus
+		phase              crdv1alpha1.BucketPhase
+		expectedTransition bool
+		expectedLastError  string
+	}{
+		{
+			name:               "first phase",
+			phase:              crdv1alpha1.BucketPhaseProvisioning,
+			expectedTransition: true,
+		},
+		{
+			name: "failed to ready clears the last error",
+			status: &crdv1alpha1.BucketClaimStatus{
+				Phase:              crdv1alpha1.BucketPhaseFailed,
+				LastTransitionTime: &oldTransitionTime,
+				LastError:          "create failed",
+			},
+			phase:              crdv1alpha1.BucketPhaseReady,
+			expectedTransition: true,
+		},
+		{
+			name: "failed to provisioning keeps the last error",
+			status: &crdv1alpha1.BucketClaimStatus{
+				Phase:              crdv1alpha1.BucketPhaseFailed,
+				LastTransitionTime: &oldTransitionTime,
+				LastError:          "create failed",
+			},
+			phase:              crdv1alpha1.BucketPhaseProvisioning,
+			expectedTransition: true,
+			expectedLastError:  "create failed",
+		},
+		{
+			name: "same phase keeps the transition time",
+			status: &crdv1alpha1.BucketClaimStatus{
+				Phase:              crdv1alpha1.BucketPhaseProvisioning,
+				LastTransitionTime: &oldTransitionTime,
+			},
+			phase: crdv1alpha1.BucketPhaseProvisioning,
+		},
+		{
+			name: "ready to deleting",
+			status: &crdv1alpha1.BucketClaimStatus{
+				Phase:              crdv1alpha1.BucketPhaseReady,
+				LastTransitionTime: &oldTransitionTime,
+			},
+			phase:              crdv1alpha1.BucketPhaseDeleting,
+			expectedTransition: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pbc := &crdv1alpha1.PXBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     tc.status,
+			}
+			bucketClaimStatusFields(pbc).setPhase(tc.phase, reasonProvisioning, "message")
+
+			if pbc.Status.Phase != tc.phase {
+				t.Fatalf("expected phase %s, got %s", tc.phase, pbc.Status.Phase)
+			}
+			if pbc.Status.LastTransitionTime == nil {
+				t.Fatalf("expected transition time to be set")
+			}
+			if transitioned := !pbc.Status.LastTransitionTime.Equal(&oldTransitionTime); transitioned != tc.expectedTransition {
+				t.Errorf("expected transition %v, got transition time %v", tc.expectedTransition, pbc.Status.LastTransitionTime)
+			}
+			if pbc.Status.ObservedGeneration != 2 {
+				t.Errorf("expected observed generation 2, got %d", pbc.Status.ObservedGeneration)
+			}
+			if pbc.Status.LastError != tc.expectedLastError {
+				t.Errorf("expected last error %q, got %q", tc.expectedLastError, pbc.Status.LastError)
+			}
+
+			for _, conditionType := range standardConditions {
+				cond := meta.FindStatusCondition(pbc.Status.Conditions, conditionType)
+				if cond == nil {
+					t.Fatalf("expected condition %s to be set", conditionType)
+				}
+				expected := metav1.ConditionFalse
+				if phaseConditions[tc.phase] == conditionType {
+					expected = metav1.ConditionTrue
+				}
+				if cond.Status != expected {
+					t.Errorf("expected condition %s to be %s, got %s", conditionType, expected, cond.Status)
+				}
+				if cond.Reason != reasonProvisioning || cond.ObservedGeneration != 2 {
+					t.Errorf("unexpected condition %+v", cond)
+				}
+			}
+		})
+	}
+}
+
+func TestSetPhaseClearsWaitingForBucket(t *testing.T) {
+	pba := &crdv1alpha1.PXBucketAccess{}
+	s := bucketAccessStatusFields(pba)
+	s.setCondition(crdv1alpha1.ConditionWaitingForBucket, metav1.ConditionTrue, reasonWaitingForBucket, "waiting")
+
+	s.setPhase(crdv1alpha1.BucketPhaseProvisioning, reasonProvisioning, "granting access")
+	if meta.IsStatusConditionTrue(pba.Status.Conditions, crdv1alpha1.ConditionWaitingForBucket) {
+		t.Errorf("expected %s condition to be cleared", crdv1alpha1.ConditionWaitingForBucket)
+	}
+}
+
+func TestSetFailed(t *testing.T) {
+	testCases := []struct {
+		name          string
+		phase         crdv1alpha1.BucketPhase
+		expectedPhase crdv1alpha1.BucketPhase
+		expectedTrue  []string
+	}{
+		{
+			name:          "provisioning fails",
+			phase:         crdv1alpha1.BucketPhaseProvisioning,
+			expectedPhase: crdv1alpha1.BucketPhaseFailed,
+			expectedTrue:  []string{crdv1alpha1.ConditionFailed},
+		},
+		{
+			name:          "ready fails",
+			phase:         crdv1alpha1.BucketPhaseReady,
+			expectedPhase: crdv1alpha1.BucketPhaseFailed,
+			expectedTrue:  []string{crdv1alpha1.ConditionFailed},
+		},
+		{
+			name:          "deleting stays deleting",
+			phase:         crdv1alpha1.BucketPhaseDeleting,
+			expectedPhase: crdv1alpha1.BucketPhaseDeleting,
+			expectedTrue:  []string{crdv1alpha1.ConditionDeleting, crdv1alpha1.ConditionFailed},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pba := &crdv1alpha1.PXBucketAccess{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+			s := bucketAccessStatusFields(pba)
+			s.setPhase(tc.phase, reasonProvisioning, "previous attempt")
+
+			if !s.setFailed(reasonGrantAccessFailed, "grant failed") {
+				t.Fatalf("expected first failure to change the status")
+			}
+
+			if pba.Status.Phase != tc.expectedPhase {
+				t.Fatalf("expected phase %s, got %s", tc.expectedPhase, pba.Status.Phase)
+			}
+			if pba.Status.LastError != "grant failed" {
+				t.Errorf("expected last error to be recorded, got %q", pba.Status.LastError)
+			}
+			if pba.Status.ObservedGeneration != 3 {
+				t.Errorf("expected observed generation 3, got %d", pba.Status.ObservedGeneration)
+			}
+
+			for _, conditionType := range standardConditions {
+				expected := false
+				for _, trueType := range tc.expectedTrue {
+					expected = expected || trueType == conditionType
+				}
+				if actual := meta.IsStatusConditionTrue(pba.Status.Conditions, conditionType); actual != expected {
+					t.Errorf("expected condition %s to be true: %v, got %v", conditionType, expected, actual)
+				}
+			}
+			cond := meta.FindStatusCondition(pba.Status.Conditions, crdv1alpha1.ConditionFailed)
+			if cond.Reason != reasonGrantAccessFailed || cond.Message != "grant failed" {
+				t.Errorf("unexpected failed condition %+v", cond)
+			}
+
+			if s.setFailed(reasonGrantAccessFailed, "grant failed") {
+				t.Errorf("expected the same failure not to change the status")
+			}
+			if !s.setFailed(reasonGrantAccessFailed, "grant failed again") {
+				t.Errorf("expected a new failure message to change the status")
+			}
+		})
+	}
+}
+
+func TestNeedsReconcile(t *testing.T) {
+	now := metav1.Now()
+	old := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			ResourceVersion: "1",
+			Generation:      1,
+			Finalizers:      []string{bucketProvisionedFinalizer},
+			Annotations:     map[string]string{allowedNamespacesKey: "tenant-b"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		update   func(pbc *crdv1alpha1.PXBucketClaim)
+		expected bool
+	}{
+		{
+			name:     "resync",
+			update:   func(pbc *crdv1alpha1.PXBucketClaim) {},
+			expected: true,
+		},
+		{
+			name: "status only",
+			update: func(pbc *crdv1alpha1.PXBucketClaim) {
+				pbc.ResourceVersion = "2"
+				bucketClaimStatusFields(pbc).setFailed(reasonCreateBucketFailed, "create failed")
+			},
+			expected: false,
+		},
+		{
+			name: "spec",
+			update: func(pbc *crdv1alpha1.PXBucketClaim) {
+				pbc.ResourceVersion = "2"
+				pbc.Generation = 2
+			},
+			expected: true,
+		},
+		{
+			name: "finalizers",
+			update: func(pbc *crdv1alpha1.PXBucketClaim) {
+				pbc.ResourceVersion = "2"
+				pbc.Finalizers = nil
+			},
+			expected: true,
+		},
+		{
+			name: "annotations",
+			update: func(pbc *crdv1alpha1.PXBucketClaim) {
+				pbc.ResourceVersion = "2"
+				pbc.Annotations[allowedNamespacesKey] = "tenant-c"
+			},
+			expected: true,
+		},
+		{
+			name: "deletion",
+			update: func(pbc *crdv1alpha1.PXBucketClaim) {
+				pbc.ResourceVersion = "2"
+				pbc.DeletionTimestamp = &now
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updated := old.DeepCopy()
+			tc.update(updated)
+			if actual := needsReconcile(old, updated); actual != tc.expected {
+				t.Fatalf("expected needsReconcile %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	testCases := []struct {
+		name               string
+		status             metav1.ConditionStatus
+		expectedTransition bool
+	}{
+		{
+			name:   "same status keeps the transition time",
+			status: metav1.ConditionTrue,
+		},
+		{
+			name:               "changed status moves the transition time",
+			status:             metav1.ConditionFalse,
+			expectedTransition: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pbc := &crdv1alpha1.PXBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Generation: 5},
+				Status: &crdv1alpha1.BucketClaimStatus{
+					Conditions: []metav1.Condition{{
+						Type:               crdv1alpha1.ConditionReady,
+						Status:             metav1.ConditionTrue,
+						ObservedGeneration: 4,
+						LastTransitionTime: oldTransitionTime,
+						Reason:             reasonBucketProvisioned,
+						Message:            "old message",
+					}},
+				},
+			}
+			bucketClaimStatusFields(pbc).setCondition(crdv1alpha1.ConditionReady, tc.status, reasonBucketFound, "new message")
+
+			cond := meta.FindStatusCondition(pbc.Status.Conditions, crdv1alpha1.ConditionReady)
+			if cond == nil {
+				t.Fatalf("expected condition to be set")
+			}
+			if cond.Status != tc.status || cond.Reason != reasonBucketFound || cond.Message != "new message" || cond.ObservedGeneration != 5 {
+				t.Errorf("expected condition to be updated, got %+v", cond)
+			}
+			if transitioned := !cond.LastTransitionTime.Equal(&oldTransitionTime); transitioned != tc.expectedTransition {
+				t.Errorf("expected transition %v, got transition time %v", tc.expectedTransition, cond.LastTransitionTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package controller
+
+// Span attributes of the reconcile loops
+const (
+	attributeBucketClaim  = "px.bucketclaim"
+	attributeBucketAccess = "px.bucketaccess"
+	attributeBucketClass  = "px.bucketclass"
+)
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateBucketClass checks the parameters of a PXBucketClass
+func ValidateBucketClass(pbclass *crdv1alpha1.PXBucketClass) error {
+	if err := validateBackendType(pbclass.Parameters[backendTypeKey]); err != nil {
+		return err
+	}
+
+	if endpoint, ok := pbclass.Parameters[endpointKey]; ok {
+		if err := validateEndpoint(endpoint); err != nil {
+			return fmt.Errorf("PXBucketClass parameter %s is invalid: %v", endpointKey, err)
+		}
+	}
+
+	if clearBucketVal, ok := pbclass.Parameters[clearBucketKey]; ok {
+		if _, err := strconv.ParseBool(clearBucketVal); err != nil {
+			return fmt.Errorf("PXBucketClass parameter %s must be a boolean, got %q", clearBucketKey, clearBucketVal)
+		}
+	}
+
+	if nameTemplate, ok := pbclass.Parameters[bucketNameTemplateKey]; ok {
+		if err := validateBucketNameTemplate(nameTemplate); err != nil {
+			return fmt.Errorf("PXBucketClass parameter %s is invalid: %v", bucketNameTemplateKey, err)
+		}
+	}
+
+	if mode, ok := pbclass.Parameters[anonymousAccessModeKey]; ok {
+		if _, ok := anonymousAccessModes[crdv1alpha1.AnonymousAccessMode(mode)]; !ok {
+			return fmt.Errorf("PXBucketClass parameter %s must be %s, %s, %s or %s, got %q", anonymousAccessModeKey,
+				crdv1alpha1.AnonymousAccessPrivate, crdv1alpha1.AnonymousAccessReadOnly, crdv1alpha1.AnonymousAccessWriteOnly, crdv1alpha1.AnonymousAccessReadWrite, mode)
+		}
+	}
+
+	if allowOverride, ok := pbclass.Parameters[allowAnonymousAccessOverrideKey]; ok {
+		if _, err := strconv.ParseBool(allowOverride); err != nil {
+			return fmt.Errorf("PXBucketClass parameter %s must be a boolean, got %q", allowAnonymousAccessOverrideKey, allowOverride)
+		}
+	}
+
+	if recreate, ok := pbclass.Parameters[recreateLostBucketsKey]; ok {
+		if _, err := strconv.ParseBool(recreate); err != nil {
+			return fmt.Errorf("PXBucketClass parameter %s must be a boolean, got %q", recreateLostBucketsKey, recreate)
+		}
+	}
+
+	var rotationPeriod time.Duration
+	if period, ok := pbclass.Parameters[rotationPeriodKey]; ok {
+		var err error
+		if rotationPeriod, err = parseRotationDuration(rotationPeriodKey, period); err != nil {
+			return err
+		}
+	}
+	if _, err := getRotationOverlap(pbclass, rotationPeriod); err != nil {
+		return err
+	}
+
+	if pbclass.Region != "" {
+		if errs := validation.IsDNS1123Label(pbclass.Region); len(errs) > 0 {
+			return fmt.Errorf("PXBucketClass region %q is invalid: %s", pbclass.Region, strings.Join(errs, ", "))
+		}
+	}
+
+	switch pbclass.DeletionPolicy {
+	case crdv1alpha1.PXBucketClaimDelete, crdv1alpha1.PXBucketClaimRetain:
+	default:
+		return fmt.Errorf("PXBucketClass deletionPolicy must be %s or %s, got %q", crdv1alpha1.PXBucketClaimDelete, crdv1alpha1.PXBucketClaimRetain, pbclass.DeletionPolicy)
+	}
+
+	return nil
+}
+
+// ValidateBucketClaim checks the spec of a PXBucketClaim
+func ValidateBucketClaim(pbc *crdv1alpha1.PXBucketClaim) error {
+	if pbc.Spec.BucketClassName == "" {
+		return fmt.Errorf("PXBucketClaim must reference a PXBucketClass or a default PXBucketClass must be set")
+	}
+
+	if pbc.Spec.BucketName != "" && pbc.Spec.ExistingBucketName != "" {
+		return fmt.Errorf("PXBucketClaim must not set both bucketName and existingBucketName")
+	}
+
+	if pbc.Spec.ExistingBucketName == "" {
+		if pbc.Spec.Region != "" || pbc.Spec.Endpoint != "" || pbc.Spec.DeletionPolicy != "" {
+			return fmt.Errorf("PXBucketClaim region, endpoint and deletionPolicy are only valid with existingBucketName")
+		}
+		return nil
+	}
+
+	if pbc.Spec.Region != "" {
+		if errs := validation.IsDNS1123Label(pbc.Spec.Region); len(errs) > 0 {
+			return fmt.Errorf("PXBucketClaim region %q is invalid: %s", pbc.Spec.Region, strings.Join(errs, ", "))
+		}
+	}
+
+	if pbc.Spec.Endpoint != "" {
+		if err := validateEndpoint(pbc.Spec.Endpoint); err != nil {
+			return fmt.Errorf("PXBucketClaim endpoint is invalid: %v", err)
+		}
+	}
+
+	switch pbc.Spec.DeletionPolicy {
+	case "", crdv1alpha1.PXBucketClaimDelete, crdv1alpha1.PXBucketClaimRetain:
+	default:
+		return fmt.Errorf("PXBucketClaim deletionPolicy must be %s or %s, got %q", crdv1alpha1.PXBucketClaimDelete, crdv1alpha1.PXBucketClaimRetain, pbc.Spec.DeletionPolicy)
+	}
+
+	return nil
+}
+
+// ValidateBucketAccess checks the spec of a PXBucketAccess
+func ValidateBucketAccess(pba *crdv1alpha1.PXBucketAccess) error {
+	if pba.Spec.BucketClassName == "" {
+		return fmt.Errorf("PXBucketAccess must reference a PXBucketClass or a default PXBucketClass must be set")
+	}
+
+	if (pba.Spec.BucketClaimName == "") == (pba.Spec.ExistingBucketId == "") {
+		return fmt.Errorf("PXBucketAccess must set exactly one of bucketClaimName and existingBucketId")
+	}
+
+	if _, ok := accessModeActions[getAccessMode(pba)]; !ok {
+		return fmt.Errorf("PXBucketAccess accessMode must be %s, %s or %s, got %q",
+			crdv1alpha1.BucketAccessReadOnly, crdv1alpha1.BucketAccessReadWrite, crdv1alpha1.BucketAccessWriteOnly, pba.Spec.AccessMode)
+	}
+
+	if name := pba.Spec.CredentialsSecretName; name != "" {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("PXBucketAccess credentialsSecretName %q is invalid: %s", name, strings.Join(errs, ", "))
+		}
+	}
+
+	if format := pba.Spec.CredentialsFormat; format != "" {
+		if err := validateCredentialsFormat(format); err != nil {
+			return fmt.Errorf("PXBucketAccess credentialsFormat is invalid: %v", err)
+		}
+	}
+
+	if pba.Spec.RotationPeriod != nil {
+		if err := validateRotationPeriod(pba.Spec.RotationPeriod.Duration); err != nil {
+			return fmt.Errorf("PXBucketAccess rotationPeriod is invalid: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateBucket checks the spec of a PXBucket
+func ValidateBucket(pb *crdv1alpha1.PXBucket) error {
+	if pb.Spec.BucketID == "" {
+		return fmt.Errorf("PXBucket bucketId must be set")
+	}
+
+	if err := validateBackendType(pb.Spec.BackendType); err != nil {
+		return fmt.Errorf("PXBucket backendType is invalid: %v", err)
+	}
+
+	if pb.Spec.Endpoint != "" {
+		if err := validateEndpoint(pb.Spec.Endpoint); err != nil {
+			return fmt.Errorf("PXBucket endpoint is invalid: %v", err)
+		}
+	}
+
+	switch pb.Spec.DeletionPolicy {
+	case crdv1alpha1.PXBucketClaimDelete, crdv1alpha1.PXBucketClaimRetain:
+	default:
+		return fmt.Errorf("PXBucket deletionPolicy must be %s or %s, got %q", crdv1alpha1.PXBucketClaimDelete, crdv1alpha1.PXBucketClaimRetain, pb.Spec.DeletionPolicy)
+	}
+
+	return nil
+}
+
+// ValidateBucketAccessClass checks the templates and credentials format of a PXBucketAccessClass
+func ValidateBucketAccessClass(pbaclass *crdv1alpha1.PXBucketAccessClass) error {
+	if pbaclass.PolicyTemplate != "" {
+		if err := validatePolicyTemplate(pbaclass.PolicyTemplate); err != nil {
+			return fmt.Errorf("PXBucketAccessClass policyTemplate is invalid: %v", err)
+		}
+	}
+
+	if pbaclass.CredentialsFormat != "" {
+		if err := validateCredentialsFormat(pbaclass.CredentialsFormat); err != nil {
+			return fmt.Errorf("PXBucketAccessClass credentialsFormat is invalid: %v", err)
+		}
+	}
+
+	if pbaclass.CredentialsFormat == crdv1alpha1.CredentialsFormatTemplate && len(pbaclass.CredentialsTemplate) == 0 {
+		return fmt.Errorf("PXBucketAccessClass credentialsTemplate must be set for credentialsFormat %s", crdv1alpha1.CredentialsFormatTemplate)
+	}
+	if err := validateCredentialsTemplate(pbaclass.CredentialsTemplate); err != nil {
+		return fmt.Errorf("PXBucketAccessClass credentialsTemplate is invalid: %v", err)
+	}
+
+	return nil
+}
+
+// ValidateBucketClaimUpdate checks that the spec of a provisioned PXBucketClaim is unchanged.
+// Only the anonymous access mode may be changed.
+func ValidateBucketClaimUpdate(oldPbc, newPbc *crdv1alpha1.PXBucketClaim) error {
+	oldSpec, newSpec := oldPbc.Spec, newPbc.Spec
+	oldSpec.AnonymousAccessMode, newSpec.AnonymousAccessMode = "", ""
+	if isBucketProvisioned(oldPbc) && !reflect.DeepEqual(oldSpec, newSpec) {
+		return fmt.Errorf("PXBucketClaim spec is immutable once the bucket is provisioned, except for anonymousAccessMode")
+	}
+
+	return nil
+}
+
+// ValidateBucketAccessUpdate checks that the spec of a granted PXBucketAccess is unchanged.
+// Only the rotation period and the credentials format may be changed.
+func ValidateBucketAccessUpdate(oldPba, newPba *crdv1alpha1.PXBucketAccess) error {
+	oldSpec, newSpec := oldPba.Spec, newPba.Spec
+	oldSpec.RotationPeriod, newSpec.RotationPeriod = nil, nil
+	oldSpec.CredentialsFormat, newSpec.CredentialsFormat = "", ""
+	if oldPba.Status != nil && oldPba.Status.AccessGranted && !reflect.DeepEqual(oldSpec, newSpec) {
+		return fmt.Errorf("PXBucketAccess spec is immutable once access is granted, except for rotationPeriod and credentialsFormat")
+	}
+
+	return nil
+}
+
+func validateBackendType(backendType string) error {
+	if backendType == "" {
+		return fmt.Errorf("PXBucketClass parameter %s is unset", backendTypeKey)
+	}
+
+	if _, ok := allowedDrivers[backendType]; !ok {
+		return fmt.Errorf("PXBucketClass parameter %s is invalid. Possible values are: %v", backendTypeKey, allowedDrivers)
+	}
+
+	return nil
+}
+
+// validateEndpoint accepts either a URL with an http or https scheme or a bare host[:port]
+func validateEndpoint(endpoint string) error {
+	host := endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("scheme must be http or https, got %q", u.Scheme)
+		}
+		host = u.Host
+	}
+
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		host = h
+	}
+	if host == "" {
+		return fmt.Errorf("host is empty")
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return fmt.Errorf("invalid host %q: %s", host, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
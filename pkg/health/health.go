@@ -0,0 +1,151 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+)
+
+const (
+	componentNameHealth = correlation.Component("pkg/health")
+
+	// LivenessPath is the path of the liveness endpoint
+	LivenessPath = "/healthz"
+	// ReadinessPath is the path of the readiness endpoint
+	ReadinessPath = "/readyz"
+
+	// checkTimeout bounds the time a single check may take
+	checkTimeout = 5 * time.Second
+)
+
+var (
+	logrus = correlation.NewPackageLogger(componentNameHealth)
+)
+
+// Check returns an error if the checked component is unhealthy
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker aggregates the liveness and readiness checks of the controller.
+// A failed liveness check gets the pod restarted, a failed readiness check
+// only takes it out of service. Readiness includes all liveness checks.
+type Checker struct {
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+// NewChecker returns a new Checker without checks
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// AddLivenessCheck adds a check that restarts the pod when it fails
+func (c *Checker) AddLivenessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadinessCheck adds a check that takes the pod out of service when it fails
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, namedCheck{name: name, check: check})
+}
+
+// Handle adds an HTTP health check handler as a liveness check. Responses
+// other than 200 fail the check. It allows the leader election to register
+// its health check.
+func (c *Checker) Handle(pattern string, handler http.Handler) {
+	c.AddLivenessCheck(path.Base(pattern), func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pattern, nil)
+		if err != nil {
+			return err
+		}
+		rw := &responseRecorder{header: make(http.Header), code: http.StatusOK}
+		handler.ServeHTTP(rw, req)
+		if rw.code != http.StatusOK {
+			return fmt.Errorf("%s", bytes.TrimSpace(rw.body.Bytes()))
+		}
+		return nil
+	})
+}
+
+// LivenessHandler serves the result of the liveness checks
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		checks := append([]namedCheck{}, c.liveness...)
+		c.mu.RUnlock()
+		serveChecks(w, r, "healthz", checks)
+	})
+}
+
+// ReadinessHandler serves the result of the liveness and readiness checks
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		checks := append(append([]namedCheck{}, c.liveness...), c.readiness...)
+		c.mu.RUnlock()
+		serveChecks(w, r, "readyz", checks)
+	})
+}
+
+// serveChecks runs the checks and writes one line per check, in the format
+// of the Kubernetes API server health endpoints
+func serveChecks(w http.ResponseWriter, r *http.Request, endpoint string, checks []namedCheck) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	var failed []string
+	for _, nc := range checks {
+		if err := nc.check(ctx); err != nil {
+			fmt.Fprintf(&out, "[-]%s failed: %v\n", nc.name, err)
+			failed = append(failed, nc.name)
+			continue
+		}
+		fmt.Fprintf(&out, "[+]%s ok\n", nc.name)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if len(failed) > 0 {
+		logrus.Warnf("%s check failed: %v", endpoint, failed)
+		fmt.Fprintf(&out, "%s check failed\n", endpoint)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		fmt.Fprintf(&out, "%s check passed\n", endpoint)
+	}
+	w.Write(out.Bytes())
+}
+
+// responseRecorder captures the response of a health check handler
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.code = code
+}
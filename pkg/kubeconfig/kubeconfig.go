@@ -0,0 +1,79 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	componentNameKubeconfig = correlation.Component("pkg/kubeconfig")
+)
+
+var (
+	logrus = correlation.NewPackageLogger(componentNameKubeconfig)
+)
+
+// Config represents a configuration for loading the Kubernetes client config
+type Config struct {
+	// Kubeconfig is the path of the kubeconfig file. Several files may be
+	// listed like in the KUBECONFIG environment variable.
+	Kubeconfig string
+	// QPS and Burst limit the requests of each client to the API server.
+	// The client-go defaults are used if they are not set.
+	QPS   float32
+	Burst int
+}
+
+// Load returns the Kubernetes client config. The kubeconfig file is used if
+// set, then the in-cluster config, then the default loading rules of kubectl
+// so that the controller can run out of cluster.
+func Load(cfg *Config) (*rest.Config, error) {
+	config, err := load(cfg.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.QPS > 0 {
+		config.QPS = cfg.QPS
+	}
+	if cfg.Burst > 0 {
+		config.Burst = cfg.Burst
+	}
+	return config, nil
+}
+
+func load(kubeconfig string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		// A single file must exist, files of a list are merged like by kubectl
+		if paths := filepath.SplitList(kubeconfig); len(paths) == 1 {
+			rules.ExplicitPath = paths[0]
+		} else {
+			rules.Precedence = paths
+		}
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig %s: %v", kubeconfig, err)
+		}
+		logrus.Infof("using kubeconfig %s", kubeconfig)
+		return config, nil
+	}
+
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		return config, nil
+	}
+	if err != rest.ErrNotInCluster {
+		return nil, err
+	}
+
+	config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("not running in a cluster and failed to load the default kubeconfig: %v", err)
+	}
+	logrus.Infof("not running in a cluster, using the default kubeconfig")
+	return config, nil
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	namespace = "px_object_controller"
+)
+
+// Operations recorded by the reconcile metrics
+const (
+	OperationCreate = "create"
+	OperationDelete = "delete"
+	OperationGrant  = "grant"
+	OperationRevoke = "revoke"
+)
+
+var (
+	// Registry holds all metrics served by the controller
+	Registry = prometheus.NewRegistry()
+
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of backend operations of the reconcile loops by operation and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		},
+		[]string{"operation", "result"},
+	)
+
+	reconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of failed backend operations of the reconcile loops by operation.",
+		},
+		[]string{"operation"},
+	)
+
+	sdkRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "sdk_request_duration_seconds",
+			Help:      "Latency of Openstorage SDK requests by method, backend type and gRPC status code.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+		[]string{"method", "backend_type", "code"},
+	)
+
+	leader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "leader",
+			Help:      "Whether this replica is the leader running the reconcile loops.",
+		},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		reconcileDuration,
+		reconcileErrors,
+		sdkRequestDuration,
+		leader,
+	)
+	registerWorkqueueMetrics()
+}
+
+// ObserveOperation records the duration and result of a backend operation
+// of the reconcile loops
+func ObserveOperation(operation string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+		reconcileErrors.WithLabelValues(operation).Inc()
+	}
+	reconcileDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
+
+// ObserveSDKRequest records the latency and status code of an SDK request
+func ObserveSDKRequest(method, backendType string, duration time.Duration, err error) {
+	sdkRequestDuration.WithLabelValues(method, backendType, status.Code(err).String()).Observe(duration.Seconds())
+}
+
+// SetLeader records whether this replica is the leader
+func SetLeader(isLeader bool) {
+	if isLeader {
+		leader.Set(1)
+	} else {
+		leader.Set(0)
+	}
+}
+
+// ObjectKey identifies a group of PXBucketClaims or PXBucketAccesses
+type ObjectKey struct {
+	Phase string
+	Class string
+}
+
+// ObjectCounter returns the number of PXBucketClaims and PXBucketAccesses by
+// phase and class
+type ObjectCounter func() (claims, accesses map[ObjectKey]int, err error)
+
+var (
+	bucketClaimsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "bucket_claims"),
+		"Number of PXBucketClaims by phase and class.",
+		[]string{"phase", "class"}, nil,
+	)
+	bucketAccessesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "bucket_accesses"),
+		"Number of PXBucketAccesses by phase and class.",
+		[]string{"phase", "class"}, nil,
+	)
+)
+
+// objectCollector counts the PXBucketClaims and PXBucketAccesses on scrape
+type objectCollector struct {
+	counter ObjectCounter
+}
+
+// RegisterObjectCounter registers the gauges of PXBucketClaims and
+// PXBucketAccesses by phase and class
+func RegisterObjectCounter(counter ObjectCounter) error {
+	return Registry.Register(&objectCollector{counter: counter})
+}
+
+func (c *objectCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bucketClaimsDesc
+	ch <- bucketAccessesDesc
+}
+
+func (c *objectCollector) Collect(ch chan<- prometheus.Metric) {
+	claims, accesses, err := c.counter()
+	if err != nil {
+		logrus.Errorf("failed to count bucket claims and accesses: %v", err)
+		return
+	}
+	for key, count := range claims {
+		ch <- prometheus.MustNewConstMetric(bucketClaimsDesc, prometheus.GaugeValue, float64(count), key.Phase, key.Class)
+	}
+	for key, count := range accesses {
+		ch <- prometheus.MustNewConstMetric(bucketAccessesDesc, prometheus.GaugeValue, float64(count), key.Phase, key.Class)
+	}
+}
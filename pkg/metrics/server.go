@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	componentNameMetrics = correlation.Component("pkg/metrics")
+
+	// MetricsPath is the path of the metrics endpoint
+	MetricsPath = "/metrics"
+)
+
+var (
+	logrus = correlation.NewPackageLogger(componentNameMetrics)
+)
+
+// Config represents a configuration for creating a metrics server
+type Config struct {
+	Port string
+}
+
+// Server serves the metrics of the controller
+type Server struct {
+	config *Config
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+// New returns a new metrics server
+func New(cfg *Config) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	return &Server{
+		config: cfg,
+		mux:    mux,
+		server: &http.Server{
+			Addr:    ":" + cfg.Port,
+			Handler: mux,
+		},
+	}
+}
+
+// Handle serves an additional handler next to the metrics, such as the
+// health checks. It must be called before Run.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run serves metrics until stopCh is closed
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	go func() {
+		<-stopCh
+		if err := s.server.Shutdown(context.Background()); err != nil {
+			logrus.Errorf("failed to shut down metrics server: %v", err)
+		}
+	}()
+
+	logrus.Infof("starting metrics server on port %s", s.config.Port)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
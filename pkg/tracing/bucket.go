@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/bucket"
+)
+
+// bucketDriver traces the calls of the embedded SDK server to a bucket driver.
+// The SDK server neither passes the request context to the drivers nor accepts
+// interceptors, so the traceparent sent with a request is handed over by the
+// client interceptor of the same process, by driver method and bucket ID.
+type bucketDriver struct {
+	bucket.BucketDriver
+}
+
+// inflight holds the traceparent headers of the bucket requests being served,
+// by driver method and bucket ID
+var inflight = struct {
+	sync.Mutex
+	traceparents map[string][]string
+}{traceparents: make(map[string][]string)}
+
+// WrapBucketDriver returns a bucket driver that traces the bucket handlers
+// of the embedded SDK server
+func WrapBucketDriver(d bucket.BucketDriver) bucket.BucketDriver {
+	return &bucketDriver{BucketDriver: d}
+}
+
+// bucketRequestKey returns the driver method and bucket ID of an SDK bucket
+// request, or an empty string for other requests
+func bucketRequestKey(req interface{}) string {
+	switch r := req.(type) {
+	case *api.BucketCreateRequest:
+		return "CreateBucket/" + r.GetName()
+	case *api.BucketDeleteRequest:
+		return "DeleteBucket/" + r.GetBucketId()
+	case *api.BucketGrantAccessRequest:
+		return "GrantBucketAccess/" + r.GetBucketId()
+	case *api.BucketRevokeAccessRequest:
+		return "RevokeBucketAccess/" + r.GetBucketId()
+	}
+	return ""
+}
+
+// beginBucketRequest records the traceparent of a bucket request until the
+// returned function is called once the request is done
+func beginBucketRequest(key, traceparent string) func() {
+	inflight.Lock()
+	inflight.traceparents[key] = append(inflight.traceparents[key], traceparent)
+	inflight.Unlock()
+
+	return func() {
+		inflight.Lock()
+		defer inflight.Unlock()
+		traceparents := inflight.traceparents[key]
+		for i, t := range traceparents {
+			if t == traceparent {
+				traceparents = append(traceparents[:i], traceparents[i+1:]...)
+				break
+			}
+		}
+		if len(traceparents) == 0 {
+			delete(inflight.traceparents, key)
+		} else {
+			inflight.traceparents[key] = traceparents
+		}
+	}
+}
+
+// bucketRequestContext returns a context continuing the trace of the latest
+// request for the driver method and bucket ID. The driver span starts a new
+// trace if the request was sent by another process.
+func bucketRequestContext(key string) context.Context {
+	inflight.Lock()
+	defer inflight.Unlock()
+	ctx := context.Background()
+	if traceparents := inflight.traceparents[key]; len(traceparents) > 0 {
+		ctx = ContextWithTraceparent(ctx, traceparents[len(traceparents)-1])
+	}
+	return ctx
+}
+
+func (d *bucketDriver) startSpan(method, bucketID string) *Span {
+	_, span := Start(bucketRequestContext(method+"/"+bucketID), "BucketDriver/"+method,
+		String("px.backend_type", d.String()),
+		String("px.bucket_id", bucketID),
+	)
+	return span
+}
+
+func (d *bucketDriver) CreateBucket(name string, region string, endpoint string, anonymousBucketAccessMode api.AnonymousBucketAccessMode) (string, error) {
+	span := d.startSpan("CreateBucket", name)
+	span.SetAttributes(String("px.region", region))
+	id, err := d.BucketDriver.CreateBucket(name, region, endpoint, anonymousBucketAccessMode)
+	span.End(err)
+	return id, err
+}
+
+func (d *bucketDriver) DeleteBucket(id string, region string, endpoint string, clearBucket bool) error {
+	span := d.startSpan("DeleteBucket", id)
+	err := d.BucketDriver.DeleteBucket(id, region, endpoint, clearBucket)
+	span.End(err)
+	return err
+}
+
+func (d *bucketDriver) GrantBucketAccess(id string, accountName string, accessPolicy string) (string, *bucket.BucketAccessCredentials, error) {
+	span := d.startSpan("GrantBucketAccess", id)
+	span.SetAttributes(String("px.account_name", accountName))
+	accountID, creds, err := d.BucketDriver.GrantBucketAccess(id, accountName, accessPolicy)
+	span.End(err)
+	return accountID, creds, err
+}
+
+func (d *bucketDriver) RevokeBucketAccess(id string, accountID string) error {
+	span := d.startSpan("RevokeBucketAccess", id)
+	span.SetAttributes(String("px.account_id", accountID))
+	err := d.BucketDriver.RevokeBucketAccess(id, accountID)
+	span.End(err)
+	return err
+}
@@ -0,0 +1,257 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tracesPath is the path of the OTLP/HTTP traces endpoint of a collector
+	tracesPath = "/v1/traces"
+
+	exportInterval  = 5 * time.Second
+	exportBatchSize = 512
+	exportQueueSize = 2048
+	exportTimeout   = 10 * time.Second
+
+	statusCodeError = 2
+)
+
+// Config represents a configuration for exporting spans
+type Config struct {
+	// Endpoint is the base URL of an OTLP/HTTP collector, such as
+	// http://otel-collector:4318. Tracing is disabled if it is empty.
+	Endpoint string
+	// ServiceName is the service.name resource attribute of the spans
+	ServiceName string
+}
+
+// Exporter sends batches of ended spans to an OTLP/HTTP collector in the JSON
+// encoding of the protocol
+type Exporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+	stop        chan struct{}
+	done        chan struct{}
+
+	shutdownOnce sync.Once
+}
+
+// Init enables tracing and starts exporting spans to the collector of cfg.
+// It is a no-op if no endpoint is configured.
+func Init(cfg *Config) error {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid OTLP endpoint %q, expected a http or https URL", cfg.Endpoint)
+	}
+
+	e := &Exporter{
+		url:         strings.TrimSuffix(cfg.Endpoint, "/") + tracesPath,
+		serviceName: cfg.ServiceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, exportQueueSize),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	exporter = e
+	logrus.Infof("exporting traces to %s", e.url)
+	return nil
+}
+
+// export queues an ended span. Spans are dropped while the queue is full so
+// that a slow collector never blocks the controller.
+func (e *Exporter) export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		logrus.Debugf("dropping span %s, export queue is full", span.name)
+	}
+}
+
+// Shutdown exports the spans that are still queued and stops exporting. It
+// is a no-op if tracing is disabled.
+func Shutdown(ctx context.Context) error {
+	e := exporter
+	if e == nil {
+		return nil
+	}
+	e.shutdownOnce.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to export queued spans: %v", ctx.Err())
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-e.stop:
+			e.flush(batch)
+			return
+		}
+		e.sendBatch(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush exports the batch and all spans left in the queue
+func (e *Exporter) flush(batch []*Span) {
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		default:
+			if len(batch) > 0 {
+				e.sendBatch(batch)
+			}
+			return
+		}
+		e.sendBatch(batch)
+		batch = batch[:0]
+	}
+}
+
+func (e *Exporter) sendBatch(batch []*Span) {
+	if err := e.send(batch); err != nil {
+		logrus.Errorf("failed to export %d spans: %v", len(batch), err)
+	}
+}
+
+func (e *Exporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP ExportTraceServiceRequest in the JSON encoding
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *Exporter) request(spans []*Span) *otlpRequest {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: "github.com/portworx/px-object-controller"}}
+	for _, s := range spans {
+		scope.Spans = append(scope.Spans, s.otlp())
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: e.serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{scope},
+		}},
+	}
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              int(s.kind),
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for _, a := range s.attributes {
+		span.Attributes = append(span.Attributes, otlpAttribute{Key: a.Key, Value: otlpValue{StringValue: a.Value}})
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+	return span
+}
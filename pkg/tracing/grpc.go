@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/libopenstorage/openstorage/api/server/sdk"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// traceparentKey is the metadata key of the W3C Trace Context
+const traceparentKey = "traceparent"
+
+// UnaryClientInterceptor traces SDK requests as client spans and propagates
+// the trace to the SDK server
+func UnaryClientInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if !Enabled() {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	name := strings.TrimPrefix(method, "/")
+	ctx, span := start(ctx, name, spanKindClient,
+		String("rpc.system", "grpc"),
+		String("rpc.service", path.Dir(name)),
+		String("rpc.method", path.Base(name)),
+	)
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(sdk.ContextDriverKey); len(values) > 0 {
+			span.SetAttributes(String("px.backend_type", values[0]))
+		}
+	}
+	traceparent := span.traceparent()
+	ctx = metadata.AppendToOutgoingContext(ctx, traceparentKey, traceparent)
+	// Drivers of the embedded SDK server pick up the trace by bucket
+	if key := bucketRequestKey(req); key != "" {
+		defer beginBucketRequest(key, traceparent)()
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	span.End(err)
+	return err
+}
@@ -0,0 +1,165 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+)
+
+const (
+	componentNameTracing = correlation.Component("pkg/tracing")
+
+	// AttributeCorrelationID is the attribute of the correlation ID of the
+	// request, which is also logged by the correlation log hook
+	AttributeCorrelationID = "px.correlation_id"
+)
+
+var (
+	logrus = correlation.NewPackageLogger(componentNameTracing)
+
+	// exporter exports the ended spans. Tracing is disabled while it is nil.
+	exporter *Exporter
+)
+
+type spanKind int
+
+// Span kinds of the OTLP protocol
+const (
+	spanKindInternal spanKind = 1
+	spanKindClient   spanKind = 3
+)
+
+type spanContextKeyType struct{}
+
+var spanContextKey = spanContextKeyType{}
+
+// Attribute is a string attribute of a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation of a trace. All methods of a nil Span, returned while
+// tracing is disabled, are no-ops.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     spanKind
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	err        error
+}
+
+// Enabled returns true if spans are exported
+func Enabled() bool {
+	return exporter != nil
+}
+
+// Start starts a span as a child of the span in ctx, if any, and returns a
+// context holding the new span
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return start(ctx, name, spanKindInternal, attributes...)
+}
+
+func start(ctx context.Context, name string, kind spanKind, attributes ...Attribute) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+
+	span := &Span{
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	if rc := correlation.RequestContextFromContextValue(ctx); rc != nil && rc.ID != "" {
+		span.attributes = append(span.attributes, String(AttributeCorrelationID, rc.ID))
+	}
+	return context.WithValue(ctx, spanContextKey, span), span
+}
+
+// SpanFromContext returns the current span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey).(*Span)
+	return span
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// End ends the span with the result of its operation and queues it for export
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	s.err = err
+	s.mu.Unlock()
+	if e := exporter; e != nil {
+		e.export(s)
+	}
+}
+
+// traceparent returns the W3C Trace Context header of the span
+func (s *Span) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// ContextWithTraceparent returns a context whose current span is the remote
+// span of a W3C Trace Context header, so that spans started from it continue
+// the trace of the caller. ctx is returned unchanged if the header is invalid.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	span, ok := parseTraceparent(traceparent)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey, span)
+}
+
+// parseTraceparent returns the remote span of a version 00 W3C Trace Context
+// header. The span is only used as a parent and is never ended.
+func parseTraceparent(traceparent string) (*Span, bool) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, false
+	}
+	span := &Span{}
+	if _, err := hex.Decode(span.traceID[:], []byte(parts[1])); err != nil || span.traceID == [16]byte{} {
+		return nil, false
+	}
+	if _, err := hex.Decode(span.spanID[:], []byte(parts[2])); err != nil || span.spanID == [8]byte{} {
+		return nil, false
+	}
+	return span, true
+}
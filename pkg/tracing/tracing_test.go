@@ -0,0 +1,215 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/bucket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type testDriver struct {
+	bucket.BucketDriver
+}
+
+func (d *testDriver) String() string {
+	return "TestDriver"
+}
+
+func (d *testDriver) CreateBucket(name string, region string, endpoint string, anonymousBucketAccessMode api.AnonymousBucketAccessMode) (string, error) {
+	return name, nil
+}
+
+// collectSpans enables tracing with a test collector. The returned function
+// shuts tracing down and returns the export requests received.
+func collectSpans(t *testing.T) func() []otlpRequest {
+	var mu sync.Mutex
+	var requests []otlpRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tracesPath {
+			t.Errorf("expected spans to be posted to %s, got %s", tracesPath, r.URL.Path)
+		}
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("expected JSON payload, got %s", contentType)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	t.Cleanup(collector.Close)
+
+	if err := Init(&Config{Endpoint: collector.URL, ServiceName: "px-object-controller-test"}); err != nil {
+		t.Fatalf("failed to initialize tracing: %v", err)
+	}
+	t.Cleanup(func() { exporter = nil })
+
+	return func() []otlpRequest {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := Shutdown(ctx); err != nil {
+			t.Fatalf("failed to shut down tracing: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func spansByName(requests []otlpRequest) map[string]otlpSpan {
+	spans := make(map[string]otlpSpan)
+	for _, req := range requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func TestBucketDriverSpanContinuesSDKRequest(t *testing.T) {
+	collected := collectSpans(t)
+	driver := WrapBucketDriver(&testDriver{})
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if values := md.Get(traceparentKey); len(values) != 1 {
+			t.Errorf("expected one traceparent to be sent, got %v", values)
+		}
+		// The embedded SDK server calls the driver without the request context
+		_, err := driver.CreateBucket("px-os-claim", "us-east-1", "", api.AnonymousBucketAccessMode_Private)
+		return err
+	}
+
+	ctx, parent := Start(context.Background(), "processBucket")
+	err := UnaryClientInterceptor(ctx, "/openstorage.api.OpenStorageBucket/Create",
+		&api.BucketCreateRequest{Name: "px-os-claim"}, &api.BucketCreateResponse{}, nil, invoker)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End(nil)
+
+	inflight.Lock()
+	if len(inflight.traceparents) != 0 {
+		t.Errorf("expected finished requests to be forgotten, got %v", inflight.traceparents)
+	}
+	inflight.Unlock()
+
+	spans := spansByName(collected())
+	reconcile, ok := spans["processBucket"]
+	if !ok {
+		t.Fatalf("expected reconcile span to be exported, got %v", spans)
+	}
+	client, ok := spans["openstorage.api.OpenStorageBucket/Create"]
+	if !ok {
+		t.Fatalf("expected SDK client span to be exported, got %v", spans)
+	}
+	driverSpan, ok := spans["BucketDriver/CreateBucket"]
+	if !ok {
+		t.Fatalf("expected driver span to be exported, got %v", spans)
+	}
+
+	if client.TraceID != reconcile.TraceID || driverSpan.TraceID != reconcile.TraceID {
+		t.Errorf("expected all spans in trace %s, got client %s and driver %s", reconcile.TraceID, client.TraceID, driverSpan.TraceID)
+	}
+	if client.ParentSpanID != reconcile.SpanID {
+		t.Errorf("expected client span to be a child of %s, got %s", reconcile.SpanID, client.ParentSpanID)
+	}
+	if driverSpan.ParentSpanID != client.SpanID {
+		t.Errorf("expected driver span to be a child of %s, got %s", client.SpanID, driverSpan.ParentSpanID)
+	}
+}
+
+func TestBucketDriverSpanWithoutSDKRequest(t *testing.T) {
+	collected := collectSpans(t)
+	driver := WrapBucketDriver(&testDriver{})
+
+	if _, err := driver.CreateBucket("px-os-claim", "us-east-1", "", api.AnonymousBucketAccessMode_Private); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	span, ok := spansByName(collected())["BucketDriver/CreateBucket"]
+	if !ok {
+		t.Fatalf("expected driver span to be exported")
+	}
+	if span.ParentSpanID != "" {
+		t.Errorf("expected driver span to start a new trace, got parent %s", span.ParentSpanID)
+	}
+}
+
+func TestExporterPayload(t *testing.T) {
+	collected := collectSpans(t)
+
+	_, span := Start(context.Background(), "createBucket", String("px.bucketclaim", "default/claim"))
+	span.End(errors.New("bucket already exists"))
+
+	requests := collected()
+	if len(requests) != 1 || len(requests[0].ResourceSpans) != 1 {
+		t.Fatalf("expected one export request with one resource, got %+v", requests)
+	}
+	rs := requests[0].ResourceSpans[0]
+	if attrs := rs.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || attrs[0].Value.StringValue != "px-object-controller-test" {
+		t.Errorf("expected service.name resource attribute, got %+v", attrs)
+	}
+	if len(rs.ScopeSpans) != 1 || len(rs.ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("expected one span, got %+v", rs.ScopeSpans)
+	}
+	if name := rs.ScopeSpans[0].Scope.Name; name != "github.com/portworx/px-object-controller" {
+		t.Errorf("unexpected scope %s", name)
+	}
+
+	got := rs.ScopeSpans[0].Spans[0]
+	if len(got.TraceID) != 32 || len(got.SpanID) != 16 || got.ParentSpanID != "" {
+		t.Errorf("unexpected span IDs %+v", got)
+	}
+	if got.Kind != int(spanKindInternal) {
+		t.Errorf("expected internal span, got kind %d", got.Kind)
+	}
+	if got.StartTimeUnixNano == "" || got.EndTimeUnixNano < got.StartTimeUnixNano {
+		t.Errorf("unexpected span times %s - %s", got.StartTimeUnixNano, got.EndTimeUnixNano)
+	}
+	if len(got.Attributes) != 1 || got.Attributes[0].Key != "px.bucketclaim" || got.Attributes[0].Value.StringValue != "default/claim" {
+		t.Errorf("unexpected span attributes %+v", got.Attributes)
+	}
+	if got.Status.Code != statusCodeError || got.Status.Message != "bucket already exists" {
+		t.Errorf("expected error status, got %+v", got.Status)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	span, ok := parseTraceparent(valid)
+	if !ok {
+		t.Fatalf("expected %s to be parsed", valid)
+	}
+	if span.traceparent() != valid {
+		t.Errorf("expected %s, got %s", valid, span.traceparent())
+	}
+
+	for _, traceparent := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		if _, ok := parseTraceparent(traceparent); ok {
+			t.Errorf("expected %q to be invalid", traceparent)
+		}
+	}
+}
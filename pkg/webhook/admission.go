@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The types below mirror the admission.k8s.io/v1 AdmissionReview wire format.
+// Only the fields used by the webhook are included.
+
+const (
+	admissionAPIVersion = "admission.k8s.io/v1"
+	admissionKind       = "AdmissionReview"
+)
+
+// Operation is the type of resource operation being checked for admission control
+type Operation string
+
+const (
+	// Create is the CREATE admission operation
+	Create Operation = "CREATE"
+	// Update is the UPDATE admission operation
+	Update Operation = "UPDATE"
+	// Delete is the DELETE admission operation
+	Delete Operation = "DELETE"
+)
+
+// AdmissionReview describes an admission review request/response
+type AdmissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *AdmissionRequest  `json:"request,omitempty"`
+	Response        *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest describes the admission.Attributes for the admission request
+type AdmissionRequest struct {
+	UID       types.UID               `json:"uid"`
+	Kind      metav1.GroupVersionKind `json:"kind"`
+	Name      string                  `json:"name,omitempty"`
+	Namespace string                  `json:"namespace,omitempty"`
+	Operation Operation               `json:"operation"`
+	Object    runtime.RawExtension    `json:"object,omitempty"`
+	OldObject runtime.RawExtension    `json:"oldObject,omitempty"`
+}
+
+// PatchType is the type of patch being used to represent the mutated object
+type PatchType string
+
+const (
+	// PatchTypeJSONPatch is the JSONPatch patch type
+	PatchTypeJSONPatch PatchType = "JSONPatch"
+)
+
+// AdmissionResponse describes an admission response
+type AdmissionResponse struct {
+	UID       types.UID      `json:"uid"`
+	Allowed   bool           `json:"allowed"`
+	Result    *metav1.Status `json:"status,omitempty"`
+	Patch     []byte         `json:"patch,omitempty"`
+	PatchType *PatchType     `json:"patchType,omitempty"`
+}
+
+// jsonPatchOperation is a single JSONPatch operation
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
@@ -0,0 +1,346 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	clientset "github.com/portworx/px-object-controller/client/clientset/versioned"
+	"github.com/portworx/px-object-controller/pkg/controller"
+	"github.com/portworx/px-object-controller/pkg/kubeconfig"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	componentNameWebhook = correlation.Component("pkg/webhook")
+
+	// ValidatePath is the path of the validating webhook
+	ValidatePath = "/validate"
+
+	// MutatePath is the path of the mutating webhook
+	MutatePath = "/mutate"
+)
+
+var (
+	logrus = correlation.NewPackageLogger(componentNameWebhook)
+)
+
+// Config represents a configuration for creating a webhook server
+type Config struct {
+	// KubeConfig is the config of the Kubernetes clients. It is loaded by
+	// kubeconfig.Load with its defaults if not set.
+	KubeConfig *rest.Config
+	Port       string
+	CertFile   string
+	KeyFile    string
+}
+
+// Server represents an admission webhook server
+type Server struct {
+	config *Config
+
+	k8sBucketClient clientset.Interface
+	k8sClient       kubernetes.Interface
+	server          *http.Server
+}
+
+// New returns a new webhook server
+func New(cfg *Config) (*Server, error) {
+	config := cfg.KubeConfig
+	if config == nil {
+		var err error
+		config, err = kubeconfig.Load(&kubeconfig.Config{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	k8sBucketClient, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	k8sClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:          cfg,
+		k8sBucketClient: k8sBucketClient,
+		k8sClient:       k8sClient,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, func(w http.ResponseWriter, r *http.Request) { s.serve(w, r, s.validate) })
+	mux.HandleFunc(MutatePath, func(w http.ResponseWriter, r *http.Request) { s.serve(w, r, s.mutate) })
+	s.server = &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: mux,
+	}
+
+	return s, nil
+}
+
+// Run serves admission requests until stopCh is closed
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	go func() {
+		<-stopCh
+		if err := s.server.Shutdown(context.Background()); err != nil {
+			logrus.Errorf("failed to shut down webhook server: %v", err)
+		}
+	}()
+
+	logrus.Infof("starting webhook server on port %s", s.config.Port)
+	if err := s.server.ListenAndServeTLS(s.config.CertFile, s.config.KeyFile); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// admitFunc admits a request and optionally returns a JSONPatch for the object
+type admitFunc func(ctx context.Context, req *AdmissionRequest) ([]byte, error)
+
+// serve decodes an AdmissionReview, runs admit on its request and writes the response
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, admit admitFunc) {
+	ctx := correlation.WithCorrelationContext(r.Context(), "px-object-controller/pkg/webhook")
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := &AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	req := review.Request
+	resp := &AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+	patch, err := admit(ctx, req)
+	if err != nil {
+		logrus.WithContext(ctx).Infof("denied %s of %s %s/%s: %v", req.Operation, req.Kind.Kind, req.Namespace, req.Name, err)
+		resp.Allowed = false
+		resp.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		}
+	} else if patch != nil {
+		patchType := PatchTypeJSONPatch
+		resp.Patch = patch
+		resp.PatchType = &patchType
+	}
+
+	out, err := json.Marshal(&AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionAPIVersion,
+			Kind:       admissionKind,
+		},
+		Response: resp,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode admission review: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(out); err != nil {
+		logrus.WithContext(ctx).Errorf("failed to write admission response: %v", err)
+	}
+}
+
+// validate checks PXBucketClasses, PXBucketAccessClasses, PXBuckets, PXBucketClaims and PXBucketAccesses
+func (s *Server) validate(ctx context.Context, req *AdmissionRequest) ([]byte, error) {
+	return nil, s.validateObject(ctx, req)
+}
+
+func (s *Server) validateObject(ctx context.Context, req *AdmissionRequest) error {
+	if req.Operation != Create && req.Operation != Update {
+		return nil
+	}
+
+	switch req.Kind.Kind {
+	case "PXBucketClass":
+		pbclass := &crdv1alpha1.PXBucketClass{}
+		if err := json.Unmarshal(req.Object.Raw, pbclass); err != nil {
+			return err
+		}
+		return controller.ValidateBucketClass(pbclass)
+
+	case "PXBucketAccessClass":
+		pbaclass := &crdv1alpha1.PXBucketAccessClass{}
+		if err := json.Unmarshal(req.Object.Raw, pbaclass); err != nil {
+			return err
+		}
+		return controller.ValidateBucketAccessClass(pbaclass)
+
+	case "PXBucket":
+		pb := &crdv1alpha1.PXBucket{}
+		if err := json.Unmarshal(req.Object.Raw, pb); err != nil {
+			return err
+		}
+		return controller.ValidateBucket(pb)
+
+	case "PXBucketClaim":
+		pbc := &crdv1alpha1.PXBucketClaim{}
+		if err := json.Unmarshal(req.Object.Raw, pbc); err != nil {
+			return err
+		}
+		if req.Operation == Update {
+			oldPbc := &crdv1alpha1.PXBucketClaim{}
+			if err := json.Unmarshal(req.OldObject.Raw, oldPbc); err != nil {
+				return err
+			}
+			// Metadata updates, such as finalizer removal, are always allowed
+			if reflect.DeepEqual(oldPbc.Spec, pbc.Spec) {
+				return nil
+			}
+			if err := controller.ValidateBucketClaimUpdate(oldPbc, pbc); err != nil {
+				return err
+			}
+		}
+		if err := controller.ValidateBucketClaim(pbc); err != nil {
+			return err
+		}
+		return s.validateBucketClassExists(ctx, pbc.Spec.BucketClassName)
+
+	case "PXBucketAccess":
+		pba := &crdv1alpha1.PXBucketAccess{}
+		if err := json.Unmarshal(req.Object.Raw, pba); err != nil {
+			return err
+		}
+		if req.Operation == Update {
+			oldPba := &crdv1alpha1.PXBucketAccess{}
+			if err := json.Unmarshal(req.OldObject.Raw, oldPba); err != nil {
+				return err
+			}
+			// Metadata updates, such as finalizer removal, are always allowed
+			if reflect.DeepEqual(oldPba.Spec, pba.Spec) {
+				return nil
+			}
+			if err := controller.ValidateBucketAccessUpdate(oldPba, pba); err != nil {
+				return err
+			}
+		}
+		if err := controller.ValidateBucketAccess(pba); err != nil {
+			return err
+		}
+		if pba.Spec.BucketAccessClassName != "" {
+			if err := s.validateBucketAccessClassExists(ctx, pba.Spec.BucketAccessClassName); err != nil {
+				return err
+			}
+		}
+		return s.validateBucketClassExists(ctx, pba.Spec.BucketClassName)
+	}
+
+	return nil
+}
+
+func (s *Server) validateBucketClassExists(ctx context.Context, name string) error {
+	_, err := s.k8sBucketClient.ObjectV1alpha1().PXBucketClasses().Get(ctx, name, metav1.GetOptions{})
+	if k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("PXBucketClass %s does not exist", name)
+	}
+	return err
+}
+
+func (s *Server) validateBucketAccessClassExists(ctx context.Context, name string) error {
+	_, err := s.k8sBucketClient.ObjectV1alpha1().PXBucketAccessClasses().Get(ctx, name, metav1.GetOptions{})
+	if k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("PXBucketAccessClass %s does not exist", name)
+	}
+	return err
+}
+
+// mutate fills in the default PXBucketClass of PXBucketClaims and PXBucketAccesses
+// created without spec.bucketClassName
+func (s *Server) mutate(ctx context.Context, req *AdmissionRequest) ([]byte, error) {
+	if req.Operation != Create {
+		return nil, nil
+	}
+
+	switch req.Kind.Kind {
+	case "PXBucketClaim":
+		pbc := &crdv1alpha1.PXBucketClaim{}
+		if err := json.Unmarshal(req.Object.Raw, pbc); err != nil {
+			return nil, err
+		}
+		if pbc.Spec.BucketClassName != "" {
+			return nil, nil
+		}
+
+	case "PXBucketAccess":
+		pba := &crdv1alpha1.PXBucketAccess{}
+		if err := json.Unmarshal(req.Object.Raw, pba); err != nil {
+			return nil, err
+		}
+		if pba.Spec.BucketClassName != "" {
+			return nil, nil
+		}
+
+	default:
+		return nil, nil
+	}
+
+	className, err := s.getDefaultBucketClassName(ctx, req.Namespace)
+	if err != nil {
+		// Leave it to validation to reject the object
+		logrus.WithContext(ctx).Infof("no default bucketclass for namespace %s: %v", req.Namespace, err)
+		return nil, nil
+	}
+	return bucketClassPatch(req.Object.Raw, className)
+}
+
+// bucketClassPatch returns a JSONPatch adding spec.bucketClassName to the raw
+// object. Only the class is added so that the rest of the spec, including
+// fields unknown to this version of the API, is left as is.
+func bucketClassPatch(raw []byte, className string) ([]byte, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+
+	op := jsonPatchOperation{
+		Op:    "add",
+		Path:  "/spec/bucketClassName",
+		Value: className,
+	}
+	// The parent of an added member must exist
+	if spec, ok := obj["spec"]; !ok || string(spec) == "null" {
+		op = jsonPatchOperation{
+			Op:    "add",
+			Path:  "/spec",
+			Value: map[string]string{"bucketClassName": className},
+		}
+	}
+	return json.Marshal([]jsonPatchOperation{op})
+}
+
+func (s *Server) getDefaultBucketClassName(ctx context.Context, namespace string) (string, error) {
+	ns, err := s.k8sClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	classList, err := s.k8sBucketClient.ObjectV1alpha1().PXBucketClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	classes := make([]*crdv1alpha1.PXBucketClass, 0, len(classList.Items))
+	for i := range classList.Items {
+		classes = append(classes, &classList.Items[i])
+	}
+
+	return controller.DefaultBucketClassName(classes, ns)
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	crdv1alpha1 "github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
+	"github.com/portworx/px-object-controller/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidate(t *testing.T) {
+	class := &crdv1alpha1.PXBucketClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "class",
+		},
+		DeletionPolicy: crdv1alpha1.PXBucketClaimDelete,
+		Parameters: map[string]string{
+			"object.portworx.io/backend-type": "S3Driver",
+			"object.portworx.io/endpoint":     "s3.us-west-2.amazonaws.com",
+		},
+	}
+	s := &Server{
+		k8sBucketClient: fake.NewSimpleClientset(class),
+	}
+
+	provisioned := &crdv1alpha1.PXBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "default", Finalizers: []string{"backup.example.com/protect"}},
+		Spec:       crdv1alpha1.BucketClaimSpec{BucketClassName: "class"},
+		Status:     &crdv1alpha1.BucketClaimStatus{Provisioned: true},
+	}
+	renamed := provisioned.DeepCopy()
+	renamed.Spec.BucketClassName = "other"
+	finalized := provisioned.DeepCopy()
+	finalized.Finalizers = nil
+
+	testCases := []struct {
+		name      string
+		kind      string
+		operation Operation
+		object    runtime.Object
+		oldObject runtime.Object
+		allowed   bool
+	}{
+		{
+			name:      "valid bucket class",
+			kind:      "PXBucketClass",
+			operation: Create,
+			object:    class,
+			allowed:   true,
+		},
+		{
+			name:      "bucket class with invalid clear-bucket",
+			kind:      "PXBucketClass",
+			operation: Create,
+			object: &crdv1alpha1.PXBucketClass{
+				DeletionPolicy: crdv1alpha1.PXBucketClaimDelete,
+				Parameters: map[string]string{
+					"object.portworx.io/backend-type": "S3Driver",
+					"object.portworx.io/clear-bucket": "sometimes",
+				},
+			},
+			allowed: false,
+		},
+		{
+			name:      "bucket claim with missing class",
+			kind:      "PXBucketClaim",
+			operation: Create,
+			object: &crdv1alpha1.PXBucketClaim{
+				Spec: crdv1alpha1.BucketClaimSpec{BucketClassName: "missing"},
+			},
+			allowed: false,
+		},
+		{
+			name:      "bucket access with claim and existing bucket",
+			kind:      "PXBucketAccess",
+			operation: Create,
+			object: &crdv1alpha1.PXBucketAccess{
+				Spec: crdv1alpha1.BucketAccessSpec{
+					BucketClassName:  "class",
+					BucketClaimName:  "claim",
+					ExistingBucketId: "px-os-bucket",
+				},
+			},
+			allowed: false,
+		},
+		{
+			name:      "spec change of provisioned claim",
+			kind:      "PXBucketClaim",
+			operation: Update,
+			object:    renamed,
+			oldObject: provisioned,
+			allowed:   false,
+		},
+		{
+			name:      "metadata change of provisioned claim",
+			kind:      "PXBucketClaim",
+			operation: Update,
+			object:    finalized,
+			oldObject: provisioned,
+			allowed:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &AdmissionRequest{
+				UID:       "uid",
+				Kind:      metav1.GroupVersionKind{Group: "object.portworx.io", Version: "v1alpha1", Kind: tc.kind},
+				Operation: tc.operation,
+				Object:    runtime.RawExtension{Raw: mustMarshal(t, tc.object)},
+			}
+			if tc.oldObject != nil {
+				req.OldObject = runtime.RawExtension{Raw: mustMarshal(t, tc.oldObject)}
+			}
+
+			rec := httptest.NewRecorder()
+			body := mustMarshal(t, &AdmissionReview{Request: req})
+			s.serve(rec, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader(body)), s.validate)
+
+			review := &AdmissionReview{}
+			if err := json.Unmarshal(rec.Body.Bytes(), review); err != nil {
+				t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+			}
+			if review.Response == nil || review.Response.UID != req.UID {
+				t.Fatalf("unexpected response: %+v", review.Response)
+			}
+			if review.Response.Allowed != tc.allowed {
+				t.Fatalf("expected allowed=%v, got %+v", tc.allowed, review.Response.Result)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, obj interface{}) []byte {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed to marshal %v: %v", obj, err)
+	}
+	return data
+}
+
+func TestBucketClassPatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		object   string
+		expected string
+	}{
+		{
+			name:     "spec without class",
+			object:   `{"kind":"PXBucketClaim","spec":{"deletionPolicy":"Retain","futureField":true}}`,
+			expected: `[{"op":"add","path":"/spec/bucketClassName","value":"default"}]`,
+		},
+		{
+			name:     "empty spec",
+			object:   `{"kind":"PXBucketAccess","spec":{}}`,
+			expected: `[{"op":"add","path":"/spec/bucketClassName","value":"default"}]`,
+		},
+		{
+			name:     "no spec",
+			object:   `{"kind":"PXBucketClaim"}`,
+			expected: `[{"op":"add","path":"/spec","value":{"bucketClassName":"default"}}]`,
+		},
+		{
+			name:     "null spec",
+			object:   `{"kind":"PXBucketClaim","spec":null}`,
+			expected: `[{"op":"add","path":"/spec","value":{"bucketClassName":"default"}}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := bucketClassPatch([]byte(tc.object), "default")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(patch) != tc.expected {
+				t.Fatalf("expected patch %s, got %s", tc.expected, patch)
+			}
+		})
+	}
+}
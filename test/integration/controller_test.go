@@ -358,6 +358,10 @@ func PreProvsionedBasic(tc *types.TestCase) func(*testing.T) {
 		}
 
 		// Import existing bucket
+		err = util.AllowExistingBuckets(objectClient, className, bucketClaim.Status.BucketID)
+		if err != nil {
+			t.Fatalf("failed to allow existing bucket in bucket class: %v", err)
+		}
 		accessName := fmt.Sprintf("pos-access-%s", randID)
 		err = util.CreateImportedBucketAccess(objectClient, tc.TestConfig.Namespace, accessName, className, bucketClaim.Status.BucketID)
 		if err != nil {
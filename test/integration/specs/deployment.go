@@ -174,12 +174,17 @@ func CreatePXObjectControllerDeployment(k8sClient *kubernetes.Clientset, ec *Env
 			{
 				APIGroups: []string{""},
 				Resources: []string{"secrets"},
-				Verbs:     []string{"get", "list", "create", "delete", "update"},
+				Verbs:     []string{"get", "list", "create", "delete", "update", "patch"},
 			},
 			{
 				APIGroups: []string{"object.portworx.io"},
-				Resources: []string{"pxbucketclaims", "pxbucketaccesses", "pxbucketclasses"},
-				Verbs:     []string{"list", "watch", "create", "update", "patch", "get"},
+				Resources: []string{"pxbucketclaims", "pxbucketaccesses", "pxbucketclasses", "pxbuckets", "pxbucketaccessclasses"},
+				Verbs:     []string{"list", "watch", "create", "update", "patch", "get", "delete"},
+			},
+			{
+				APIGroups: []string{"object.portworx.io"},
+				Resources: []string{"pxbucketclaims/status", "pxbucketaccesses/status", "pxbuckets/status"},
+				Verbs:     []string{"update", "patch", "get"},
 			},
 			{
 				APIGroups: []string{""},
@@ -2,6 +2,7 @@ package util
 
 import (
 	"context"
+	"strings"
 
 	"github.com/portworx/px-object-controller/client/apis/objectservice/v1alpha1"
 	clientset "github.com/portworx/px-object-controller/client/clientset/versioned"
@@ -34,6 +35,22 @@ func CreateBucketClass(objectClient *clientset.Clientset, name string, retainBuc
 	return nil
 }
 
+// AllowExistingBuckets allows bucket accesses using the bucket class to import the given buckets
+func AllowExistingBuckets(objectClient *clientset.Clientset, name string, bucketIDs ...string) error {
+	bucketClass, err := objectClient.ObjectV1alpha1().PXBucketClasses().Get(context.Background(), name, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	bucketClass.Parameters["object.portworx.io/allowed-existing-buckets"] = strings.Join(bucketIDs, ",")
+	_, err = objectClient.ObjectV1alpha1().PXBucketClasses().Update(context.Background(), bucketClass, v1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // DeleteBucketClass deletes an bucket class
 func DeleteBucketClass(objectClient *clientset.Clientset, name string) error {
 	err := objectClient.ObjectV1alpha1().PXBucketClasses().Delete(context.Background(), name, v1.DeleteOptions{})